@@ -0,0 +1,96 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// probedAgentVersions are tried, newest first, against agents that do not list their commands
+var probedAgentVersions = []string{"v2", "v1"}
+
+type availableCommandResponse struct {
+	AvailableCommands []string `json:"AvailableCommands"`
+}
+
+// agentIntrospection reads the ECS agent introspection API at the newest version the agent offers, and records which
+// version that was under "ApiVersion"
+func (e *Expvar) agentIntrospection(ctx context.Context, base string) (map[string]interface{}, error) {
+	b, err := e.getBody(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	var m availableCommandResponse
+	if err := json.Unmarshal(b, &m); err != nil || len(m.AvailableCommands) == 0 {
+		return e.probeAgentVersion(ctx, base)
+	}
+	version := newestAgentVersion(m.AvailableCommands)
+	ret := make(map[string]interface{}, len(m.AvailableCommands)+1)
+	for _, subCommand := range m.AvailableCommands {
+		if subCommand == "/license" {
+			continue
+		}
+		if v := agentVersion(subCommand); v != "" && v != version {
+			continue
+		}
+		val, err := e.single(ctx, base+subCommand)
+		if err != nil {
+			ret[subCommand] = err
+		} else {
+			ret[subCommand] = val
+		}
+	}
+	ret["ApiVersion"] = version
+	return ret, nil
+}
+
+func (e *Expvar) probeAgentVersion(ctx context.Context, base string) (map[string]interface{}, error) {
+	var lastErr error
+	for _, version := range probedAgentVersions {
+		ret := make(map[string]interface{}, 3)
+		for _, subCommand := range []string{"/" + version + "/metadata", "/" + version + "/tasks"} {
+			val, err := e.single(ctx, base+subCommand)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			ret[subCommand] = val
+		}
+		if len(ret) > 0 {
+			ret["ApiVersion"] = version
+			return ret, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// agentVersion is "v1" for "/v1/metadata", or empty for commands outside a version
+func agentVersion(command string) string {
+	parts := strings.SplitN(strings.TrimPrefix(command, "/"), "/", 2)
+	if len(parts) < 2 || agentVersionNumber(parts[0]) < 0 {
+		return ""
+	}
+	return parts[0]
+}
+
+func agentVersionNumber(version string) int {
+	if !strings.HasPrefix(version, "v") {
+		return -1
+	}
+	n, err := strconv.Atoi(version[1:])
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func newestAgentVersion(commands []string) string {
+	newest := ""
+	for _, command := range commands {
+		if v := agentVersion(command); v != "" && agentVersionNumber(v) > agentVersionNumber(newest) {
+			newest = v
+		}
+	}
+	return newest
+}
@@ -0,0 +1,53 @@
+package awsexpvar
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAgentIntrospectionNewestVersion(t *testing.T) {
+	srv := serveJSON(t, map[string]string{
+		"/":            `{"AvailableCommands": ["/v1/metadata", "/v2/metadata", "/v2/tasks", "/license"]}`,
+		"/v1/metadata": `{"Version": "old"}`,
+		"/v2/metadata": `{"Cluster": "default"}`,
+		"/v2/tasks":    `{"Tasks": "none"}`,
+	})
+	e := &Expvar{Client: testClient()}
+	ret, err := e.agentIntrospection(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret["ApiVersion"] != "v2" {
+		t.Errorf("ApiVersion %v, want v2", ret["ApiVersion"])
+	}
+	for _, command := range []string{"/v1/metadata", "/license"} {
+		if _, exists := ret[command]; exists {
+			t.Errorf("%s fetched", command)
+		}
+	}
+	if _, exists := ret["/v2/metadata"]; !exists {
+		t.Error("/v2/metadata missing")
+	}
+}
+
+func TestAgentIntrospectionProbe(t *testing.T) {
+	srv := serveJSON(t, map[string]string{
+		"/":            `{}`,
+		"/v1/metadata": `{"Cluster": "default"}`,
+		"/v1/tasks":    `{"Tasks": "none"}`,
+	})
+	e := &Expvar{Client: testClient()}
+	ret, err := e.agentIntrospection(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret["ApiVersion"] != "v1" {
+		t.Errorf("ApiVersion %v, want v1 from probing", ret["ApiVersion"])
+	}
+}
+
+func TestNewestAgentVersion(t *testing.T) {
+	if v := newestAgentVersion([]string{"/v9/tasks", "/v10/tasks", "/license"}); v != "v10" {
+		t.Errorf("newest %q, want v10", v)
+	}
+}
@@ -0,0 +1,53 @@
+package awsexpvar
+
+import (
+	"errors"
+	"strings"
+)
+
+// ARN is an Amazon Resource Name split into its fields: arn:partition:service:region:account-id:resource
+type ARN struct {
+	Partition string `json:"partition"`
+	Service   string `json:"service"`
+	Region    string `json:"region,omitempty"`
+	AccountID string `json:"account-id,omitempty"`
+	// Resource is everything after the account, such as "task/default/0123456789abcdef"
+	Resource string `json:"resource"`
+}
+
+// ParseARN splits arn into its fields.  The resource may itself contain colons.
+func ParseARN(arn string) (ARN, error) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ARN{}, errors.New("not an ARN: " + arn)
+	}
+	if parts[1] == "" || parts[2] == "" || parts[5] == "" {
+		return ARN{}, errors.New("ARN missing partition, service or resource: " + arn)
+	}
+	return ARN{
+		Partition: parts[1],
+		Service:   parts[2],
+		Region:    parts[3],
+		AccountID: parts[4],
+		Resource:  parts[5],
+	}, nil
+}
+
+// String is the inverse of ParseARN
+func (a ARN) String() string {
+	return "arn:" + a.Partition + ":" + a.Service + ":" + a.Region + ":" + a.AccountID + ":" + a.Resource
+}
+
+// ResourceType is the part of Resource before its first "/" or ":", such as "task", or empty if there is none
+func (a ARN) ResourceType() string {
+	if idx := strings.IndexAny(a.Resource, "/:"); idx >= 0 {
+		return a.Resource[:idx]
+	}
+	return ""
+}
+
+// exposedARN is how the arns section shows an ARN: as it was served, next to its fields
+type exposedARN struct {
+	Raw string `json:"arn"`
+	ARN
+}
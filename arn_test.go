@@ -0,0 +1,53 @@
+package awsexpvar
+
+import "testing"
+
+func TestParseARN(t *testing.T) {
+	for _, tc := range []struct {
+		arn          string
+		want         ARN
+		resourceType string
+	}{
+		{
+			arn: "arn:aws:ecs:us-west-2:123456789012:task/default/0123456789abcdef",
+			want: ARN{Partition: "aws", Service: "ecs", Region: "us-west-2", AccountID: "123456789012",
+				Resource: "task/default/0123456789abcdef"},
+			resourceType: "task",
+		},
+		{
+			arn:          "arn:aws-cn:iam::123456789012:role/web",
+			want:         ARN{Partition: "aws-cn", Service: "iam", AccountID: "123456789012", Resource: "role/web"},
+			resourceType: "role",
+		},
+		{
+			arn: "arn:aws:logs:us-west-2:123456789012:log-group:/ecs/web:*",
+			want: ARN{Partition: "aws", Service: "logs", Region: "us-west-2", AccountID: "123456789012",
+				Resource: "log-group:/ecs/web:*"},
+			resourceType: "log-group",
+		},
+		{
+			arn:  "arn:aws:s3:::bucket",
+			want: ARN{Partition: "aws", Service: "s3", Resource: "bucket"},
+		},
+	} {
+		got, err := ParseARN(tc.arn)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.arn, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.arn, got, tc.want)
+		}
+		if got.String() != tc.arn {
+			t.Errorf("%s: String %s", tc.arn, got.String())
+		}
+		if got.ResourceType() != tc.resourceType {
+			t.Errorf("%s: resource type %q, want %q", tc.arn, got.ResourceType(), tc.resourceType)
+		}
+	}
+	for _, arn := range []string{"", "arn:aws:ecs", "urn:aws:ecs:us-west-2:123456789012:task/x", "arn::ecs:::task/x",
+		"arn:aws:ecs:us-west-2:123456789012:"} {
+		if _, err := ParseARN(arn); err == nil {
+			t.Errorf("%q parsed", arn)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package awsexpvar
+
+import (
+	"net/url"
+	"strings"
+)
+
+// arns collects every ARN the other sections expose under one set of keys, so consumers do not have to know which
+// section, or which version of which endpoint, each one came from.  ARNs are trimmed and, if percent-escaped,
+// unescaped, then shown next to their fields as ParseARN splits them.
+func arns(ret map[string]interface{}, task *taskMetadata) interface{} {
+	found := make(map[string]interface{})
+	add := func(key string, arn string) {
+		if exposed, ok := exposeARN(arn); ok {
+			found[key] = exposed
+		}
+	}
+	ecs := ret["ecs-metadata"]
+	agentMetadata := "/" + lookupString(ecs, "ApiVersion") + "/metadata"
+	if task != nil {
+		add("task", task.TaskARN)
+		add("cluster", task.Cluster)
+		containers := make(map[string]exposedARN, len(task.Containers))
+		for _, c := range task.Containers {
+			if exposed, ok := exposeARN(c.ContainerARN); ok {
+				containers[c.Name] = exposed
+			}
+		}
+		if len(containers) > 0 {
+			found["containers"] = containers
+		}
+	} else {
+		add("cluster", lookupString(ecs, agentMetadata, "Cluster"))
+	}
+	add("container-instance", lookupString(ecs, agentMetadata, "ContainerInstanceArn"))
+	if source, ok := ret["credential-source"].(credentialSource); ok && source.Source != CredentialSourceInstanceProfile {
+		add("role", source.Detail)
+	}
+	add("instance-profile", lookupString(ret["meta-data"], "iam", "info", "InstanceProfileArn"))
+	if len(found) == 0 {
+		return nil
+	}
+	return found
+}
+
+// exposeARN is false if arn, trimmed and unescaped, is not an ARN: clusters, for one, are only named in older
+// responses
+func exposeARN(arn string) (exposedARN, bool) {
+	arn = strings.TrimSpace(arn)
+	if strings.Contains(arn, "%") {
+		if unescaped, err := url.PathUnescape(arn); err == nil {
+			arn = unescaped
+		}
+	}
+	parsed, err := ParseARN(arn)
+	if err != nil {
+		return exposedARN{}, false
+	}
+	return exposedARN{Raw: arn, ARN: parsed}, true
+}
@@ -0,0 +1,65 @@
+package awssdk
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/cep21/awsexpvar"
+)
+
+// KMSAPI is the part of *kms.Client KMSCipher uses
+type KMSAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSCipher envelope encrypts snapshots: each one gets a fresh data key from KMS, is sealed with AES-GCM under it,
+// and is stored with the KMS encrypted data key in front.  Use it as Expvar.FileCipher.
+type KMSCipher struct {
+	Client KMSAPI
+	KeyID  string
+}
+
+var _ awsexpvar.SnapshotCipher = &KMSCipher{}
+
+// Encrypt returns a 4 byte big endian length, the encrypted data key, then the sealed plaintext
+func (c *KMSCipher) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	key, err := c.Client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(c.KeyID),
+		KeySpec: types.DataKeySpecAes256,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := (&awsexpvar.AESGCMCipher{Key: key.Plaintext}).Encrypt(ctx, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]byte, 4, 4+len(key.CiphertextBlob)+len(sealed))
+	binary.BigEndian.PutUint32(ret, uint32(len(key.CiphertextBlob)))
+	ret = append(ret, key.CiphertextBlob...)
+	return append(ret, sealed...), nil
+}
+
+// Decrypt asks KMS for the data key and opens the snapshot with it
+func (c *KMSCipher) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 4 {
+		return nil, errors.New("ciphertext too short")
+	}
+	keyLen := int(binary.BigEndian.Uint32(ciphertext))
+	if len(ciphertext) < 4+keyLen {
+		return nil, errors.New("ciphertext too short")
+	}
+	key, err := c.Client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: ciphertext[4 : 4+keyLen],
+		KeyId:          aws.String(c.KeyID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return (&awsexpvar.AESGCMCipher{Key: key.Plaintext}).Decrypt(ctx, ciphertext[4+keyLen:])
+}
@@ -0,0 +1,12 @@
+// Package awssdk holds the awsexpvar integrations that need aws-sdk-go-v2.  It is its own module so that
+// github.com/cep21/awsexpvar stays free of SDK dependencies for users who only want metadata over HTTP.
+//
+// Heartbeats can be published to SNS or SQS on the refresh schedule:
+//
+//	e := &awsexpvar.Expvar{}
+//	h := &awsexpvar.Heartbeat{
+//		Expvar:    e,
+//		Publisher: &awssdk.SQSPublisher{Client: sqs.NewFromConfig(cfg), QueueURL: queueURL},
+//	}
+//	go h.Run(ctx)
+package awssdk
@@ -0,0 +1,77 @@
+package awssdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/cep21/awsexpvar"
+)
+
+// IMDSAPI is the part of *imds.Client IMDSAdapter uses
+type IMDSAPI interface {
+	GetMetadata(ctx context.Context, params *imds.GetMetadataInput, optFns ...func(*imds.Options)) (*imds.GetMetadataOutput, error)
+	GetDynamicData(ctx context.Context, params *imds.GetDynamicDataInput, optFns ...func(*imds.Options)) (*imds.GetDynamicDataOutput, error)
+	GetUserData(ctx context.Context, params *imds.GetUserDataInput, optFns ...func(*imds.Options)) (*imds.GetUserDataOutput, error)
+}
+
+// IMDSAdapter serves Expvar.IMDS with the SDK's IMDS client, so the process keeps a single IMDSv2 token and one set of
+// retry settings
+type IMDSAdapter struct {
+	Client IMDSAPI
+}
+
+var _ awsexpvar.IMDSClient = &IMDSAdapter{}
+
+const (
+	metaDataPrefix = "/latest/meta-data/"
+	dynamicPrefix  = "/latest/dynamic/"
+	userDataPath   = "/latest/user-data"
+)
+
+var errUnsupportedPath = errors.New("path is not under meta-data, dynamic or user-data")
+
+// Get routes path to the SDK call for its part of the metadata tree
+func (a *IMDSAdapter) Get(ctx context.Context, path string) ([]byte, error) {
+	var body io.ReadCloser
+	var err error
+	switch {
+	case strings.HasPrefix(path, metaDataPrefix):
+		var out *imds.GetMetadataOutput
+		if out, err = a.Client.GetMetadata(ctx, &imds.GetMetadataInput{Path: strings.TrimPrefix(path, metaDataPrefix)}); err == nil {
+			body = out.Content
+		}
+	case strings.HasPrefix(path, dynamicPrefix):
+		var out *imds.GetDynamicDataOutput
+		if out, err = a.Client.GetDynamicData(ctx, &imds.GetDynamicDataInput{Path: strings.TrimPrefix(path, dynamicPrefix)}); err == nil {
+			body = out.Content
+		}
+	case path == userDataPath:
+		var out *imds.GetUserDataOutput
+		if out, err = a.Client.GetUserData(ctx, &imds.GetUserDataInput{}); err == nil {
+			body = out.Content
+		}
+	default:
+		return nil, errUnsupportedPath
+	}
+	if err != nil {
+		return nil, notFound(err)
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	return ioutil.ReadAll(body)
+}
+
+// notFound turns the SDK's 404 response error into awsexpvar.ErrNotFound
+func notFound(err error) error {
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) && statusErr.HTTPStatusCode() == http.StatusNotFound {
+		return awsexpvar.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package awssdk
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/cep21/awsexpvar"
+)
+
+// SNSAPI is the part of *sns.Client SNSPublisher uses
+type SNSAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// SNSPublisher publishes each heartbeat to an SNS topic
+type SNSPublisher struct {
+	Client   SNSAPI
+	TopicARN string
+}
+
+var _ awsexpvar.Publisher = &SNSPublisher{}
+
+// Publish sends payload as the message body
+func (p *SNSPublisher) Publish(ctx context.Context, payload []byte) error {
+	_, err := p.Client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(p.TopicARN),
+		Message:  aws.String(string(payload)),
+	})
+	return err
+}
+
+// SQSAPI is the part of *sqs.Client SQSPublisher uses
+type SQSAPI interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// SQSPublisher sends each heartbeat to an SQS queue
+type SQSPublisher struct {
+	Client   SQSAPI
+	QueueURL string
+}
+
+var _ awsexpvar.Publisher = &SQSPublisher{}
+
+// Publish sends payload as the message body
+func (p *SQSPublisher) Publish(ctx context.Context, payload []byte) error {
+	_, err := p.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(p.QueueURL),
+		MessageBody: aws.String(string(payload)),
+	})
+	return err
+}
@@ -0,0 +1,48 @@
+package awssdk
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/cep21/awsexpvar"
+)
+
+// EC2API is the part of *ec2.Client EC2Tagger uses
+type EC2API interface {
+	DescribeTags(ctx context.Context, params *ec2.DescribeTagsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTagsOutput, error)
+}
+
+// EC2Tagger reads instance tags with ec2:DescribeTags, for Expvar.TagFallback when instance metadata tags are not
+// enabled.  The instance role needs ec2:DescribeTags.
+type EC2Tagger struct {
+	Client EC2API
+}
+
+var _ awsexpvar.InstanceTagger = &EC2Tagger{}
+
+// InstanceTags returns every tag of instanceID, calling the EC2 API in the instance's region
+func (t *EC2Tagger) InstanceTags(ctx context.Context, region string, instanceID string) (map[string]string, error) {
+	ret := make(map[string]string)
+	input := &ec2.DescribeTagsInput{
+		Filters: []types.Filter{
+			{Name: aws.String("resource-id"), Values: []string{instanceID}},
+		},
+	}
+	for {
+		out, err := t.Client.DescribeTags(ctx, input, func(o *ec2.Options) {
+			o.Region = region
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range out.Tags {
+			ret[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		if aws.ToString(out.NextToken) == "" {
+			return ret, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
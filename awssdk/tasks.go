@@ -0,0 +1,57 @@
+package awssdk
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/cep21/awsexpvar"
+)
+
+// ECSAPI is the part of *ecs.Client ECSDescriber uses
+type ECSAPI interface {
+	DescribeTasks(ctx context.Context, params *ecs.DescribeTasksInput, optFns ...func(*ecs.Options)) (*ecs.DescribeTasksOutput, error)
+}
+
+// ECSDescriber enriches task metadata with ecs:DescribeTasks, for Expvar.TaskEnrichment.  The task role needs
+// ecs:DescribeTasks.
+type ECSDescriber struct {
+	Client ECSAPI
+}
+
+var _ awsexpvar.TaskDescriber = &ECSDescriber{}
+
+// DescribeTask returns the task's tags, started by, group and capacity details
+func (d *ECSDescriber) DescribeTask(ctx context.Context, region string, cluster string, taskARN string) (map[string]interface{}, error) {
+	out, err := d.Client.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(cluster),
+		Tasks:   []string{taskARN},
+		Include: []types.TaskField{types.TaskFieldTags},
+	}, func(o *ecs.Options) {
+		o.Region = region
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Tasks) == 0 {
+		if len(out.Failures) > 0 {
+			return nil, errors.New(aws.ToString(out.Failures[0].Reason))
+		}
+		return nil, errors.New("task not found")
+	}
+	task := out.Tasks[0]
+	tags := make(map[string]string, len(task.Tags))
+	for _, tag := range task.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return map[string]interface{}{
+		"tags":                 tags,
+		"startedBy":            aws.ToString(task.StartedBy),
+		"group":                aws.ToString(task.Group),
+		"capacityProviderName": aws.ToString(task.CapacityProviderName),
+		"launchType":           string(task.LaunchType),
+		"platformVersion":      aws.ToString(task.PlatformVersion),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package awsexpvar
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	errNoInterval     = errors.New("RefreshInterval must be positive")
+	errAlreadyStarted = errors.New("already started")
+)
+
+// background is the refresh goroutine run between Start and Stop
+type background struct {
+	// cancel ends the goroutine, and the Refresh it has in flight
+	cancel   context.CancelFunc
+	done     chan struct{}
+	interval chan time.Duration
+}
+
+// Start refreshes now, then every RefreshInterval in a goroutine, until Stop is called or ctx ends.  Var only ever
+// reads the refreshed copy, so scrapes never wait on IMDS.  Start fails if Validate does.  Once Stop is called or ctx
+// ends, Start may be called again.
+func (e *Expvar) Start(ctx context.Context) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	interval := e.refreshInterval()
+	if interval <= 0 {
+		return errNoInterval
+	}
+	e.mu.Lock()
+	if e.background != nil {
+		e.mu.Unlock()
+		return errAlreadyStarted
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	b := &background{
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		interval: make(chan time.Duration, 1),
+	}
+	e.background = b
+	e.mu.Unlock()
+	go e.refreshLoop(ctx, b, interval)
+	return nil
+}
+
+// Stop ends the goroutine of Start and waits for it to exit.  A refresh in flight is cancelled and discarded, so the
+// last complete snapshot is kept.
+func (e *Expvar) Stop() {
+	e.mu.Lock()
+	b := e.background
+	e.background = nil
+	e.mu.Unlock()
+	if b == nil {
+		return
+	}
+	b.cancel()
+	<-b.done
+}
+
+// SetRefreshInterval changes RefreshInterval at runtime.  A running Start picks it up for the next refresh.  The
+// interval must be positive.
+func (e *Expvar) SetRefreshInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return errNoInterval
+	}
+	e.mu.Lock()
+	e.intervalOverride = interval
+	b := e.background
+	e.mu.Unlock()
+	if b == nil {
+		return nil
+	}
+	select {
+	case b.interval <- interval:
+	default:
+	}
+	return nil
+}
+
+func (e *Expvar) refreshInterval() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.intervalOverride > 0 {
+		return e.intervalOverride
+	}
+	return e.RefreshInterval
+}
+
+// refreshLoop refreshes, then waits out the interval.  A new interval restarts the wait without refreshing early.
+// On the way out it clears e.background, unless Stop already has, so a loop that ended with ctx does not block the
+// next Start.
+func (e *Expvar) refreshLoop(ctx context.Context, b *background, interval time.Duration) {
+	defer func() {
+		e.mu.Lock()
+		if e.background == b {
+			e.background = nil
+		}
+		e.nextRefresh = time.Time{}
+		e.mu.Unlock()
+		b.cancel()
+		close(b.done)
+	}()
+	for {
+		e.setNextRefresh(time.Now().Add(interval))
+		values := e.fetch(ctx)
+		if ctx.Err() != nil {
+			// Stop or the end of ctx cut this refresh short
+			return
+		}
+		err := e.store(values)
+		if err == nil {
+			err = fetchErr(ctx, values)
+		}
+		if err != nil && e.Log != nil {
+			e.Log.Log("err", err, "unable to refresh")
+		}
+	wait:
+		for {
+			timer := time.NewTimer(time.Until(e.nextRefreshAt()))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case interval = <-b.interval:
+				timer.Stop()
+				e.setNextRefresh(time.Now().Add(interval))
+			case <-timer.C:
+				break wait
+			}
+		}
+	}
+}
+
+func (e *Expvar) setNextRefresh(at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextRefresh = at
+}
+
+func (e *Expvar) nextRefreshAt() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.nextRefresh
+}
@@ -0,0 +1,108 @@
+package awsexpvar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFamily polls the published family until it is want
+func waitFamily(t *testing.T, e *Expvar, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for publishedFamily(t, e) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("family never became %s", want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	s := serveTask(t, "web")
+	e := &Expvar{Client: testClient(), RefreshInterval: 10 * time.Millisecond}
+	if err := e.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Start(context.Background()); err != errAlreadyStarted {
+		t.Errorf("second Start: %v, want errAlreadyStarted", err)
+	}
+	waitFamily(t, e, "web")
+	s.setFamily("api")
+	waitFamily(t, e, "api")
+	e.Stop()
+	requests := atomic.LoadInt64(&s.requests)
+	s.setFamily("worker")
+	time.Sleep(50 * time.Millisecond)
+	if family := publishedFamily(t, e); family != "api" {
+		t.Errorf("family %q after Stop, want the last snapshot api", family)
+	}
+	if got := atomic.LoadInt64(&s.requests); got != requests {
+		t.Errorf("%d requests after Stop", got-requests)
+	}
+	e.Stop()
+}
+
+func TestStartWithoutInterval(t *testing.T) {
+	e := &Expvar{Client: testClient()}
+	if err := e.Start(context.Background()); err == nil {
+		e.Stop()
+		t.Fatal("Start without RefreshInterval succeeded")
+	}
+}
+
+func TestStartAfterContextEnds(t *testing.T) {
+	serveTask(t, "web")
+	e := &Expvar{Client: testClient(), RefreshInterval: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := e.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := e.Start(context.Background())
+		if err == nil {
+			break
+		}
+		if err != errAlreadyStarted || time.Now().After(deadline) {
+			t.Fatalf("Start after ctx ended: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	e.Stop()
+}
+
+func TestStopCancelsRefresh(t *testing.T) {
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", srv.URL)
+	e := &Expvar{Client: testClient(), RefreshInterval: time.Hour, Timeout: time.Minute}
+	if err := e.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	stopped := make(chan struct{})
+	go func() {
+		e.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop waited out the refresh in flight")
+	}
+	if snap := e.snapshot(); snap != nil {
+		t.Errorf("cancelled refresh stored %s", snap.encoded)
+	}
+}
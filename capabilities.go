@@ -0,0 +1,56 @@
+package awsexpvar
+
+import "os"
+
+// Capabilities reported under the "capabilities" key
+const (
+	CapabilityIMDSv1        = "imdsv1"
+	CapabilityIMDSv2        = "imdsv2"
+	CapabilityECSAgent      = "ecs-agent"
+	CapabilityTaskMetaV4    = "taskmeta-v4"
+	CapabilityTaskMetaV3    = "taskmeta-v3"
+	CapabilityContainerFile = "container-file"
+	CapabilityLambda        = "lambda"
+	CapabilityNone          = "none"
+)
+
+// capabilities lists which sources answered while building ret, so fleet tooling can inventory what each process
+// can observe.  IMDS is listed as imdsv2 when it answered with session tokens.
+func (e *Expvar) capabilities(ret map[string]interface{}) []string {
+	caps := make([]string, 0, 5)
+	if answered(ret["meta-data"]) {
+		if e.usedIMDSv2() {
+			caps = append(caps, CapabilityIMDSv2)
+		} else {
+			caps = append(caps, CapabilityIMDSv1)
+		}
+	}
+	if lookupString(ret["ecs-metadata"], "Source") == ecsSourceAgent {
+		caps = append(caps, CapabilityECSAgent)
+	}
+	if answered(ret["task-metadata"]) {
+		if _, version := e.taskMetadataURL(); version == "v3" {
+			caps = append(caps, CapabilityTaskMetaV3)
+		} else {
+			caps = append(caps, CapabilityTaskMetaV4)
+		}
+	}
+	if answered(ret["container-metadata"]) {
+		caps = append(caps, CapabilityContainerFile)
+	}
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		caps = append(caps, CapabilityLambda)
+	}
+	if len(caps) == 0 {
+		caps = append(caps, CapabilityNone)
+	}
+	return caps
+}
+
+func answered(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	_, isErr := v.(error)
+	return !isErr
+}
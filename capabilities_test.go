@@ -0,0 +1,68 @@
+package awsexpvar
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "")
+	t.Setenv("ECS_CONTAINER_METADATA_FILE", "")
+	srv := serveJSON(t, map[string]string{"/task": testTask})
+	for _, tc := range []struct {
+		name    string
+		taskURL string
+		want    []interface{}
+	}{
+		{name: "nothing answers", want: []interface{}{CapabilityNone}},
+		{name: "task metadata", taskURL: srv.URL, want: []interface{}{CapabilityTaskMetaV4}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("ECS_CONTAINER_METADATA_URI_V4", tc.taskURL)
+			ret := published(t, &Expvar{Client: testClient()})
+			if !reflect.DeepEqual(ret["capabilities"], tc.want) {
+				t.Errorf("capabilities %v, want %v", ret["capabilities"], tc.want)
+			}
+		})
+	}
+}
+
+// mapIMDS is an IMDSClient serving fixed paths.  Repeated slashes are ignored, as IMDS ignores them.
+type mapIMDS map[string]string
+
+func (m mapIMDS) Get(_ context.Context, path string) ([]byte, error) {
+	body, exists := m[strings.Replace(path, "//", "/", -1)]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return []byte(body), nil
+}
+
+func TestIMDSClient(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	e := &Expvar{Client: testClient(), IMDS: mapIMDS{
+		metadataPath:                 "instance-id",
+		metadataPath + "instance-id": "i-0123456789abcdef0",
+	}}
+	metaData, _ := published(t, e)["meta-data"].(map[string]interface{})
+	if id := metaData["instance-id"]; id != "i-0123456789abcdef0" {
+		t.Errorf("instance-id %v through the IMDS client", id)
+	}
+}
+
+func TestCapabilitiesIMDSClient(t *testing.T) {
+	e := &Expvar{IMDS: mapIMDS{
+		metadataPath:                 "instance-id",
+		metadataPath + "instance-id": "i-0123456789abcdef0",
+	}}
+	ret := e.fetch(context.Background())
+	if mode := ret["imds_mode"]; mode != IMDSModeSDK {
+		t.Errorf("imds_mode %v, want %s", mode, IMDSModeSDK)
+	}
+	caps, _ := ret["capabilities"].([]string)
+	if !containsString(caps, CapabilityIMDSv2) || containsString(caps, CapabilityIMDSv1) {
+		t.Errorf("capabilities %v, want %s", caps, CapabilityIMDSv2)
+	}
+}
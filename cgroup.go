@@ -0,0 +1,40 @@
+package awsexpvar
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// cgroupFile lists the cgroups of this process, whose paths name the container runtime on cgroup v1 hosts
+const cgroupFile = "/proc/self/cgroup"
+
+// cgroupPaths maps the DockerID of every container in the task to its cgroup path, relative to the cgroup mount
+// (/sys/fs/cgroup/<controller> on v1, /sys/fs/cgroup on v2).  Containers of a task share a parent cgroup, so the
+// paths are this container's own path with its DockerID swapped for each sibling's.  Returns nil when this
+// container's path does not contain its DockerID, as inside a cgroup namespace.
+func cgroupPaths(task *taskMetadata) interface{} {
+	b, err := ioutil.ReadFile(cgroupFile)
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		path := parts[2]
+		for _, self := range task.Containers {
+			if self.DockerID == "" || !strings.Contains(path, self.DockerID) {
+				continue
+			}
+			ret := make(map[string]string, len(task.Containers))
+			for _, c := range task.Containers {
+				if c.DockerID != "" {
+					ret[c.DockerID] = strings.Replace(path, self.DockerID, c.DockerID, 1)
+				}
+			}
+			return ret
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package awsexpvar
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+)
+
+// SnapshotCipher encrypts snapshots before they are written to disk, since they include user-data and should never
+// sit on disk in plaintext.  The awssdk module has a KMS backed implementation.
+type SnapshotCipher interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// AESGCMCipher is a SnapshotCipher using AES-GCM with a fixed key.  The nonce is prepended to the ciphertext.
+type AESGCMCipher struct {
+	// Key is 16, 24 or 32 bytes
+	Key []byte
+}
+
+var _ SnapshotCipher = &AESGCMCipher{}
+
+// AESGCMCipherFromEnv reads a base64 encoded AES key from the environment variable name
+func AESGCMCipherFromEnv(name string) (*AESGCMCipher, error) {
+	encoded := os.Getenv(name)
+	if encoded == "" {
+		return nil, errors.New(name + " is not set")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCMCipher{Key: key}, nil
+}
+
+func (c *AESGCMCipher) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.Key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext under a random nonce
+func (c *AESGCMCipher) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	aead, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens what Encrypt sealed
+func (c *AESGCMCipher) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	aead, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
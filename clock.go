@@ -0,0 +1,41 @@
+package awsexpvar
+
+import (
+	"net/http"
+	"time"
+)
+
+// clockSkew compares the Date header of the latest IMDS response to the local clock.  SigV4 rejects requests signed
+// more than five minutes off, so skew here explains signing failures elsewhere in the process.  The Date header has
+// one second resolution, so skews under a second are noise.
+type clockSkew struct {
+	IMDSDate    time.Time `json:"imds-date"`
+	LocalTime   time.Time `json:"local-time"`
+	SkewSeconds float64   `json:"skew-seconds"`
+}
+
+// recordDate compares the response Date to the middle of the request, which is the best guess at when it was written
+func (e *Expvar) recordDate(header http.Header, sent time.Time, received time.Time) {
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clock = &clockSkew{
+		IMDSDate:    date,
+		LocalTime:   local,
+		SkewSeconds: date.Sub(local).Seconds(),
+	}
+}
+
+// clockSkew is nil until IMDS answers with a Date header
+func (e *Expvar) clockSkew() interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.clock == nil {
+		return nil
+	}
+	return *e.clock
+}
@@ -0,0 +1,67 @@
+// Command awsexpvar checks AWS metadata from the command line.  Its verify mode is meant as an init container or
+// preflight step that keeps the main application from starting without the metadata it depends on:
+//
+//	awsexpvar verify -require instance-id,region,task-arn
+//
+// verify prints each required field and exits 1 if any of them could not be resolved, or 2 on bad usage.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cep21/awsexpvar"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "verify" {
+		_, _ = fmt.Fprintln(stderr, "usage: awsexpvar verify -require field[,field...]")
+		return 2
+	}
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	require := fs.String("require", "", "comma separated fields that must resolve, such as instance-id,region,task-arn")
+	timeout := fs.Duration("timeout", 5*time.Second, "how long to wait for every field")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	var fields []awsexpvar.Field
+	for _, name := range strings.Split(*require, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		field, err := awsexpvar.ParseField(name)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 2
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		_, _ = fmt.Fprintln(stderr, "verify: -require lists no fields")
+		return 2
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	var e awsexpvar.Expvar
+	values, err := e.Fetch(ctx, fields...)
+	for _, field := range fields {
+		if val, exists := values[field]; exists {
+			_, _ = fmt.Fprintf(stdout, "%s=%s\n", field, val)
+		}
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "missing required metadata:", err)
+		return 1
+	}
+	return 0
+}
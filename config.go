@@ -0,0 +1,161 @@
+package awsexpvar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// errYAMLConfig keeps YAML parsing, and its dependency, out of this module
+var errYAMLConfig = errors.New("YAML configuration files are loaded by github.com/cep21/awsexpvar/yamlconfig")
+
+// Config is the part of Expvar that operators tune from a mounted file rather than from code.  Keys are the
+// kebab-case names of the fields; unset keys leave the matching Expvar field alone.
+type Config struct {
+	// DisabledSections are top level keys, such as "user-data", that are neither fetched nor exposed
+	DisabledSections      []string `json:"disabled-sections,omitempty"`
+	SecretEnvVars         []string `json:"secret-env-vars,omitempty"`
+	RedactKeys            []string `json:"redact-keys,omitempty"`
+	EnvAllowlist          []string `json:"env-allowlist,omitempty"`
+	EnvNamesOnly          *bool    `json:"env-names-only,omitempty"`
+	LabelAllowlist        []string `json:"label-allowlist,omitempty"`
+	CrawlAllowlist        []string `json:"crawl-allowlist,omitempty"`
+	CrawlDenylist         []string `json:"crawl-denylist,omitempty"`
+	HumanSizes            *bool    `json:"human-sizes,omitempty"`
+	MetadataEndpoints     []string `json:"metadata-endpoints,omitempty"`
+	UserAgent             string   `json:"user-agent,omitempty"`
+	MaxRequestsPerRefresh int      `json:"max-requests-per-refresh,omitempty"`
+	Timeout               Duration `json:"timeout,omitempty"`
+	WalkTimeout           Duration `json:"walk-timeout,omitempty"`
+	CacheTTL              Duration `json:"cache-ttl,omitempty"`
+	RefreshInterval       Duration `json:"refresh-interval,omitempty"`
+	WalkConcurrency       int      `json:"walk-concurrency,omitempty"`
+	MaxDepth              int      `json:"max-depth,omitempty"`
+	// ScrubPatterns are regular expressions, or "default" for DefaultScrubPatterns
+	ScrubPatterns []string `json:"scrub-patterns,omitempty"`
+}
+
+// Duration is a time.Duration written the way time.ParseDuration reads it, such as "500ms"
+type Duration time.Duration
+
+// UnmarshalJSON reads a quoted duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes d as a quoted duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig reads a JSON Config from path.  Unknown keys are an error, so typos in a mounted file are not silently
+// ignored.
+func LoadConfig(path string) (*Config, error) {
+	b, err := readJSONFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(b)
+}
+
+// ParseConfig is LoadConfig for configuration that is already in memory
+func ParseConfig(b []byte) (*Config, error) {
+	var ret Config
+	if err := decodeStrict(b, &ret); err != nil {
+		return nil, err
+	}
+	if _, err := compileScrubPatterns(ret.ScrubPatterns); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// readJSONFile reads path, which must not be YAML
+func readJSONFile(path string) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return nil, errYAMLConfig
+	}
+	return ioutil.ReadFile(path)
+}
+
+func decodeStrict(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// Apply copies every setting of c onto e.  Call it before e is first used.
+func (c *Config) Apply(e *Expvar) {
+	if c.DisabledSections != nil {
+		e.DisabledSections = c.DisabledSections
+	}
+	if c.SecretEnvVars != nil {
+		e.SecretEnvVars = c.SecretEnvVars
+	}
+	if c.RedactKeys != nil {
+		e.RedactKeys = c.RedactKeys
+	}
+	// ParseConfig has already rejected patterns that do not compile
+	if patterns, err := compileScrubPatterns(c.ScrubPatterns); err == nil && c.ScrubPatterns != nil {
+		e.ScrubPatterns = patterns
+	}
+	if c.EnvAllowlist != nil {
+		e.EnvAllowlist = c.EnvAllowlist
+	}
+	if c.LabelAllowlist != nil {
+		e.LabelAllowlist = c.LabelAllowlist
+	}
+	if c.CrawlAllowlist != nil {
+		e.CrawlAllowlist = c.CrawlAllowlist
+	}
+	if c.CrawlDenylist != nil {
+		e.CrawlDenylist = c.CrawlDenylist
+	}
+	if c.EnvNamesOnly != nil {
+		e.EnvNamesOnly = *c.EnvNamesOnly
+	}
+	if c.HumanSizes != nil {
+		e.HumanSizes = *c.HumanSizes
+	}
+	if c.MetadataEndpoints != nil {
+		e.MetadataEndpoints = c.MetadataEndpoints
+	}
+	if c.UserAgent != "" {
+		e.UserAgent = c.UserAgent
+	}
+	if c.MaxRequestsPerRefresh != 0 {
+		e.MaxRequestsPerRefresh = c.MaxRequestsPerRefresh
+	}
+	if c.Timeout != 0 {
+		e.Timeout = time.Duration(c.Timeout)
+	}
+	if c.WalkTimeout != 0 {
+		e.WalkTimeout = time.Duration(c.WalkTimeout)
+	}
+	if c.CacheTTL != 0 {
+		e.CacheTTL = time.Duration(c.CacheTTL)
+	}
+	if c.RefreshInterval != 0 {
+		e.RefreshInterval = time.Duration(c.RefreshInterval)
+	}
+	if c.WalkConcurrency != 0 {
+		e.WalkConcurrency = c.WalkConcurrency
+	}
+	if c.MaxDepth != 0 {
+		e.MaxDepth = c.MaxDepth
+	}
+}
@@ -0,0 +1,50 @@
+package awsexpvar
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	c, err := ParseConfig([]byte(`{"disabled-sections": ["user-data"], "env-names-only": false, "user-agent": "web/1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Expvar{EnvNamesOnly: true, SecretEnvVars: []string{"TOKEN"}}
+	c.Apply(e)
+	if !reflect.DeepEqual(e.DisabledSections, []string{"user-data"}) {
+		t.Errorf("DisabledSections %v", e.DisabledSections)
+	}
+	if e.EnvNamesOnly {
+		t.Error("env-names-only false left EnvNamesOnly set")
+	}
+	if e.UserAgent != "web/1" {
+		t.Errorf("UserAgent %q", e.UserAgent)
+	}
+	if !reflect.DeepEqual(e.SecretEnvVars, []string{"TOKEN"}) {
+		t.Errorf("unset secret-env-vars changed SecretEnvVars to %v", e.SecretEnvVars)
+	}
+	if _, err := ParseConfig([]byte(`{"disabled-section": ["user-data"]}`)); err == nil {
+		t.Error("unknown key accepted")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "awsexpvar.json")
+	if err := ioutil.WriteFile(path, []byte(`{"metadata-endpoints": ["http://127.0.0.1:1"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.MetadataEndpoints, []string{"http://127.0.0.1:1"}) {
+		t.Errorf("MetadataEndpoints %v", c.MetadataEndpoints)
+	}
+	if _, err := LoadConfig(filepath.Join(dir, "awsexpvar.yaml")); err != errYAMLConfig {
+		t.Errorf("yaml file: %v, want errYAMLConfig", err)
+	}
+}
@@ -0,0 +1,56 @@
+package awsexpvar
+
+import "strings"
+
+// crawlSegments splits a meta-data path, such as "placement/" or "/latest/meta-data//placement/", into segments
+// relative to the meta-data root
+func crawlSegments(path string) []string {
+	path = strings.TrimPrefix(path, metadataPath)
+	ret := make([]string, 0, strings.Count(path, "/")+1)
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			ret = append(ret, segment)
+		}
+	}
+	return ret
+}
+
+// hasPrefixPath is true if prefix names path or one of the directories above it
+func hasPrefixPath(path []string, prefix []string) bool {
+	return len(prefix) <= len(path) && samePath(prefix, path[:len(prefix)])
+}
+
+// crawled is whether the meta-data crawl fetches path: nothing under CrawlDenylist, and when CrawlAllowlist is set,
+// only its paths, what is under them, and the directories leading to them
+func (e *Expvar) crawled(path string) bool {
+	segments := crawlSegments(path)
+	for _, denied := range e.CrawlDenylist {
+		if hasPrefixPath(segments, crawlSegments(denied)) {
+			return false
+		}
+	}
+	if len(e.CrawlAllowlist) == 0 {
+		return true
+	}
+	for _, allowed := range e.CrawlAllowlist {
+		allowedSegments := crawlSegments(allowed)
+		if hasPrefixPath(segments, allowedSegments) || hasPrefixPath(allowedSegments, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// crawlOverlaps describes each CrawlAllowlist path that CrawlDenylist makes unreachable
+func (e *Expvar) crawlOverlaps() []string {
+	var ret []string
+	for _, allowed := range e.CrawlAllowlist {
+		for _, denied := range e.CrawlDenylist {
+			if hasPrefixPath(crawlSegments(allowed), crawlSegments(denied)) {
+				ret = append(ret, allowed+" is in CrawlAllowlist but under "+denied+" in CrawlDenylist, so it is "+
+					"never crawled")
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,51 @@
+package awsexpvar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCrawlLists(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	e := &Expvar{
+		Client:            testClient(),
+		MetadataEndpoints: []string{fileEndpoint(t, testInstance)},
+		CrawlAllowlist:    []string{"placement/", "/latest/meta-data/instance-id"},
+		CrawlDenylist:     []string{"placement/region"},
+	}
+	metaData, _ := published(t, e)["meta-data"].(map[string]interface{})
+	if id := metaData["instance-id"]; id != "i-0123456789abcdef0" {
+		t.Errorf("allowlisted instance-id %v", id)
+	}
+	if _, exists := metaData["instance-type"]; exists {
+		t.Error("instance-type crawled outside CrawlAllowlist")
+	}
+	placement, _ := metaData["placement/"].(map[string]interface{})
+	if az := placement["availability-zone"]; az != "us-west-2a" {
+		t.Errorf("availability-zone %v under an allowlisted directory", az)
+	}
+	if _, exists := placement["region"]; exists {
+		t.Error("denylisted placement/region crawled")
+	}
+}
+
+func TestCrawled(t *testing.T) {
+	e := &Expvar{CrawlAllowlist: []string{"network/interfaces/macs/"}}
+	for path, want := range map[string]bool{
+		metadataPath + "network/":                             true,
+		metadataPath + "/network/interfaces/":                 true,
+		metadataPath + "network/interfaces/macs/0e:01/vpc-id": true,
+		metadataPath + "placement/":                           false,
+	} {
+		if got := e.crawled(path); got != want {
+			t.Errorf("%s: crawled %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestValidateCrawlOverlap(t *testing.T) {
+	e := &Expvar{CrawlAllowlist: []string{"placement/region"}, CrawlDenylist: []string{"placement/"}}
+	if err := e.Validate(); err == nil || !strings.Contains(err.Error(), "never crawled") {
+		t.Errorf("got %v, want the overlap reported", err)
+	}
+}
@@ -0,0 +1,113 @@
+package awsexpvar
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const taskRoleURL = "http://169.254.170.2"
+
+func (e *Expvar) taskRole(ctx context.Context) string {
+	client, credURL, header, err := e.credentialsRequest()
+	if err != nil {
+		return err.Error()
+	}
+	if credURL == "" {
+		return "(no-relative-url-for-task-information)"
+	}
+	b, err := e.getBodyWith(ctx, client, credURL, header)
+	if err != nil {
+		return err.Error()
+	}
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "<invalid_single_value>"
+	}
+	return m["RoleArn"]
+}
+
+// credentialsRequest resolves the container credentials endpoint the same way the SDKs do: the relative URI
+// against the ECS agent wins, otherwise the full URI with an optional authorization token.
+func (e *Expvar) credentialsRequest() (*http.Client, string, http.Header, error) {
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		return e.client(), taskRoleURL + relative, nil, nil
+	}
+	fullURI := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if fullURI == "" {
+		return nil, "", nil, nil
+	}
+	header := make(http.Header)
+	token, err := containerAuthorizationToken()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if token != "" {
+		header.Set("Authorization", token)
+	}
+	if !strings.HasPrefix(fullURI, "https://") {
+		return e.client(), fullURI, header, nil
+	}
+	client, err := e.credentialsClient()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return client, fullURI, header, nil
+}
+
+func containerAuthorizationToken() (string, error) {
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		b, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"), nil
+}
+
+// credentialsClient is only used for an https full credentials URI, so custom TLS settings never leak into the
+// metadata calls
+func (e *Expvar) credentialsClient() (*http.Client, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.credClient == nil && e.credClientErr == nil {
+		tlsConfig, err := e.credentialsTLSConfig()
+		if err != nil {
+			e.credClientErr = err
+		} else {
+			e.credClient = newHTTPClient(tlsConfig)
+		}
+	}
+	return e.credClient, e.credClientErr
+}
+
+func (e *Expvar) credentialsTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		// nolint: gosec
+		InsecureSkipVerify: e.CredentialsInsecureSkipVerify,
+	}
+	bundle := e.CredentialsCABundle
+	if bundle == "" {
+		bundle = os.Getenv("AWS_CA_BUNDLE")
+	}
+	if bundle == "" {
+		return tlsConfig, nil
+	}
+	pem, err := ioutil.ReadFile(bundle)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in " + bundle)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
@@ -0,0 +1,100 @@
+package awsexpvar
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Credential sources reported under "credential-source", named after the providers of aws-sdk-go-v2
+const (
+	CredentialSourceEnvironment     = "environment"
+	CredentialSourceSharedConfig    = "shared-config"
+	CredentialSourceWebIdentity     = "web-identity"
+	CredentialSourceContainer       = "container"
+	CredentialSourceInstanceProfile = "instance-profile"
+	CredentialSourceNone            = "none"
+)
+
+// credentialKeys are the shared config keys any of which make a profile a source of credentials
+var credentialKeys = []string{"aws_access_key_id", "role_arn", "credential_process", "sso_session", "sso_start_url",
+	"web_identity_token_file"}
+
+type credentialSource struct {
+	Source string `json:"source"`
+	// Detail is whatever names the credentials without exposing them: a profile, a role or a URI
+	Detail string `json:"detail,omitempty"`
+}
+
+// credentialSourceInfo walks the default credential chain of aws-sdk-go-v2 in the same order the SDK does, without
+// loading any credentials.  A profile only counts when it sets credentials: AWS_PROFILE naming a profile that just
+// sets a region leaves the chain to fall through to container or instance credentials.
+func credentialSourceInfo(metaData interface{}, taskRole func() string) credentialSource {
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" && os.Getenv("AWS_SECRET_ACCESS_KEY") != "" {
+		return credentialSource{Source: CredentialSourceEnvironment, Detail: "AWS_ACCESS_KEY_ID"}
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile != "" && sharedProfileHasCredentials(profile) {
+		return credentialSource{Source: CredentialSourceSharedConfig, Detail: profile}
+	}
+	if os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" {
+		return credentialSource{Source: CredentialSourceWebIdentity, Detail: os.Getenv("AWS_ROLE_ARN")}
+	}
+	if profile == "" && sharedProfileHasCredentials("default") {
+		return credentialSource{Source: CredentialSourceSharedConfig, Detail: "default"}
+	}
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return credentialSource{Source: CredentialSourceContainer, Detail: taskRole()}
+	}
+	if arn := lookupString(metaData, "iam", "info", "InstanceProfileArn"); arn != "" {
+		return credentialSource{Source: CredentialSourceInstanceProfile, Detail: arn}
+	}
+	return credentialSource{Source: CredentialSourceNone}
+}
+
+// sharedProfileHasCredentials looks for profile in the shared credentials and config files, wherever the SDK would
+func sharedProfileHasCredentials(profile string) bool {
+	home, _ := os.UserHomeDir()
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsFile == "" && home != "" {
+		credentialsFile = filepath.Join(home, ".aws", "credentials")
+	}
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if configFile == "" && home != "" {
+		configFile = filepath.Join(home, ".aws", "config")
+	}
+	return iniSectionHasKey(credentialsFile, profile) || iniSectionHasKey(configFile, "profile "+profile) ||
+		(profile == "default" && iniSectionHasKey(configFile, profile))
+}
+
+// iniSectionHasKey is true when the [section] of the ini file at path sets one of credentialKeys
+func iniSectionHasKey(path string, section string) bool {
+	if path == "" {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		key := strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+		if containsString(credentialKeys, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package awsexpvar
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialSourceInfo(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	config := "[profile region-only]\nregion = us-west-2\n\n" +
+		"[profile with-role]\nrole_arn = arn:aws:iam::123456789012:role/x\n"
+	if err := ioutil.WriteFile(configFile, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	credentialsFile := filepath.Join(dir, "credentials")
+	if err := ioutil.WriteFile(credentialsFile, []byte("[default]\naws_access_key_id = AKID\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	metaData := map[string]interface{}{
+		"iam/": map[string]interface{}{
+			"info": map[string]string{"InstanceProfileArn": "arn:aws:iam::123456789012:instance-profile/web"},
+		},
+	}
+	instance := credentialSource{
+		Source: CredentialSourceInstanceProfile,
+		Detail: "arn:aws:iam::123456789012:instance-profile/web",
+	}
+	for _, tc := range []struct {
+		name        string
+		env         map[string]string
+		credentials bool
+		want        credentialSource
+	}{
+		{
+			name: "environment keys",
+			env:  map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"},
+			want: credentialSource{Source: CredentialSourceEnvironment, Detail: "AWS_ACCESS_KEY_ID"},
+		},
+		{
+			name: "web identity",
+			env: map[string]string{"AWS_WEB_IDENTITY_TOKEN_FILE": "/var/run/token",
+				"AWS_ROLE_ARN": "arn:aws:iam::123456789012:role/web"},
+			credentials: true,
+			want:        credentialSource{Source: CredentialSourceWebIdentity, Detail: "arn:aws:iam::123456789012:role/web"},
+		},
+		{
+			name:        "default profile",
+			credentials: true,
+			want:        credentialSource{Source: CredentialSourceSharedConfig, Detail: "default"},
+		},
+		{
+			name: "container",
+			env:  map[string]string{"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "/v2/x"},
+			want: credentialSource{Source: CredentialSourceContainer, Detail: "task-role"},
+		},
+		{
+			name: "instance profile",
+			want: instance,
+		},
+		{
+			name: "environment keys before profile",
+			env:  map[string]string{"AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret", "AWS_PROFILE": "with-role"},
+			want: credentialSource{Source: CredentialSourceEnvironment, Detail: "AWS_ACCESS_KEY_ID"},
+		},
+		{
+			name: "profile with credentials",
+			env:  map[string]string{"AWS_PROFILE": "with-role"},
+			want: credentialSource{Source: CredentialSourceSharedConfig, Detail: "with-role"},
+		},
+		{
+			name:        "profile without credentials falls through",
+			env:         map[string]string{"AWS_PROFILE": "region-only"},
+			credentials: true,
+			want:        instance,
+		},
+		{
+			name: "container after a profile without credentials",
+			env:  map[string]string{"AWS_PROFILE": "region-only", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "/v2/x"},
+			want: credentialSource{Source: CredentialSourceContainer, Detail: "task-role"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_PROFILE",
+				"AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+				"AWS_CONTAINER_CREDENTIALS_FULL_URI"} {
+				t.Setenv(name, tc.env[name])
+			}
+			t.Setenv("AWS_CONFIG_FILE", configFile)
+			if tc.credentials {
+				t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+			} else {
+				t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "missing"))
+			}
+			got := credentialSourceInfo(metaData, func() string { return "task-role" })
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultEMFNamespace is used when EMFEmitter.Namespace is not set
+const defaultEMFNamespace = "awsexpvar"
+
+// EMFEmitter periodically writes the slim snapshot as CloudWatch Embedded Metric Format, so the CloudWatch agent or
+// Lambda and ECS log drivers turn it into a "Heartbeat" metric, with the metadata searchable in CloudWatch Logs,
+// without another agent
+type EMFEmitter struct {
+	Expvar *Expvar
+	// Writer receives one JSON line per emission.  Defaults to stdout.
+	Writer io.Writer
+	// Namespace of the metrics.  Defaults to "awsexpvar".
+	Namespace string
+	// Dimensions are Slim keys, such as "region" or "instanceType", to dimension every metric by.  Keys without a
+	// value are left out.
+	Dimensions []string
+	// Fields are snapshot paths, written the way Transform takes them, to emit too.  Numeric leaves become metrics
+	// and every other leaf a property.
+	Fields []string
+	// Interval between emissions.  Defaults to one minute.
+	Interval time.Duration
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit,omitempty"`
+}
+
+type emfDirective struct {
+	Namespace  string      `json:"Namespace"`
+	Dimensions [][]string  `json:"Dimensions"`
+	Metrics    []emfMetric `json:"Metrics"`
+}
+
+type emfMetadata struct {
+	Timestamp         int64          `json:"Timestamp"`
+	CloudWatchMetrics []emfDirective `json:"CloudWatchMetrics"`
+}
+
+// Run emits immediately and then every Interval until ctx is done.  Failures are logged to the Expvar's Logger and
+// retried on the next tick.
+func (m *EMFEmitter) Run(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Emit(ctx); err != nil && m.Expvar.Log != nil {
+			m.Expvar.Log.Log("err", err, "unable to emit EMF")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Emit writes a single EMF line
+func (m *EMFEmitter) Emit(ctx context.Context) error {
+	b, err := m.encode(m.Expvar.Snapshot(ctx), time.Now())
+	if err != nil {
+		return err
+	}
+	w := m.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (m *EMFEmitter) encode(snap Snapshot, now time.Time) ([]byte, error) {
+	namespace := m.Namespace
+	if namespace == "" {
+		namespace = defaultEMFNamespace
+	}
+	ret := make(map[string]interface{})
+	slim := snap.Slim()
+	for k, v := range slim {
+		ret[k] = v
+	}
+	dimensions := make([]string, 0, len(m.Dimensions))
+	for _, d := range m.Dimensions {
+		if slim[d] != "" {
+			dimensions = append(dimensions, d)
+		}
+	}
+	metrics := []emfMetric{{Name: "Heartbeat", Unit: "Count"}}
+	ret["Heartbeat"] = 1
+	for _, field := range m.Fields {
+		val := lookup(map[string]interface{}(snap), strings.Split(strings.Trim(field, "/"), "/")...)
+		switch v := val.(type) {
+		case nil:
+			continue
+		case json.Number, float64, int, int64:
+			metrics = append(metrics, emfMetric{Name: field})
+			ret[field] = v
+		case string:
+			ret[field] = strings.TrimSpace(v)
+		default:
+			ret[field] = v
+		}
+	}
+	ret["_aws"] = emfMetadata{
+		Timestamp: now.UnixNano() / int64(time.Millisecond),
+		CloudWatchMetrics: []emfDirective{{
+			Namespace:  namespace,
+			Dimensions: [][]string{dimensions},
+			Metrics:    metrics,
+		}},
+	}
+	return json.Marshal(ret)
+}
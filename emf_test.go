@@ -0,0 +1,55 @@
+package awsexpvar
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEMFEncode(t *testing.T) {
+	snap := Snapshot{
+		"meta-data":         map[string]interface{}{"instance-id": "i-0123456789abcdef0\n"},
+		"instance-identity": map[string]string{"region": "us-west-2"},
+		"task-metadata": map[string]interface{}{
+			"Family": "web",
+			"Limits": map[string]interface{}{"CPU": json.Number("0.25")},
+		},
+	}
+	m := &EMFEmitter{
+		Namespace:  "test",
+		Dimensions: []string{"region", "taskFamily", "autoScalingGroup"},
+		Fields:     []string{"task-metadata/Limits/CPU", "meta-data/instance-id", "meta-data/missing"},
+	}
+	b, err := m.encode(snap, time.Unix(1500000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		AWS struct {
+			Timestamp         int64
+			CloudWatchMetrics []emfDirective
+		} `json:"_aws"`
+		Heartbeat  int
+		Region     string  `json:"region"`
+		CPU        float64 `json:"task-metadata/Limits/CPU"`
+		InstanceID string  `json:"meta-data/instance-id"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []emfDirective{{
+		Namespace:  "test",
+		Dimensions: [][]string{{"region", "taskFamily"}},
+		Metrics:    []emfMetric{{Name: "Heartbeat", Unit: "Count"}, {Name: "task-metadata/Limits/CPU"}},
+	}}
+	if !reflect.DeepEqual(got.AWS.CloudWatchMetrics, want) {
+		t.Errorf("directives %+v, want %+v", got.AWS.CloudWatchMetrics, want)
+	}
+	if got.AWS.Timestamp != 1500000000000 {
+		t.Errorf("timestamp %d", got.AWS.Timestamp)
+	}
+	if got.Heartbeat != 1 || got.Region != "us-west-2" || got.CPU != 0.25 || got.InstanceID != "i-0123456789abcdef0" {
+		t.Errorf("values %+v", got)
+	}
+}
@@ -0,0 +1,37 @@
+package awsexpvar
+
+import (
+	"context"
+	"strings"
+)
+
+// TaskDescriber adds what only the ECS API knows about a task (tags, started by, group) to the task metadata.  The
+// awssdk module implements it with ecs:DescribeTasks.
+type TaskDescriber interface {
+	DescribeTask(ctx context.Context, region string, cluster string, taskARN string) (map[string]interface{}, error)
+}
+
+// taskEnrichment is the "task-enrichment" section, only present when TaskEnrichment is set
+func (e *Expvar) taskEnrichment(ctx context.Context, task *taskMetadata) interface{} {
+	if e.TaskEnrichment == nil || task == nil || task.TaskARN == "" {
+		return nil
+	}
+	region := arnRegion(task.TaskARN)
+	if region == "" {
+		return nil
+	}
+	val, err := e.TaskEnrichment.DescribeTask(ctx, region, task.Cluster, task.TaskARN)
+	if err != nil {
+		return err.Error()
+	}
+	return val
+}
+
+// arnRegion is the region field of arn:partition:service:region:account:resource
+func arnRegion(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[3]
+}
@@ -0,0 +1,49 @@
+package awsexpvar
+
+import "os"
+
+// Values reported under "secret-env"
+const (
+	SecretEnvInjected = "injected"
+	SecretEnvMissing  = "missing"
+)
+
+// secretEnv reports, by name only, whether each expected secret was injected.  Neither task metadata nor the agent
+// says which variables came from a valueFrom, so the names have to be given in SecretEnvVars.
+func (e *Expvar) secretEnv() interface{} {
+	if len(e.SecretEnvVars) == 0 {
+		return nil
+	}
+	ret := make(map[string]string, len(e.SecretEnvVars))
+	for _, name := range e.SecretEnvVars {
+		if _, exists := os.LookupEnv(name); exists {
+			ret[name] = SecretEnvInjected
+		} else {
+			ret[name] = SecretEnvMissing
+		}
+	}
+	return ret
+}
+
+// allowedEnv exposes the allowlisted environment variables that are set.  Everything else stays hidden.
+func (e *Expvar) allowedEnv() interface{} {
+	if len(e.EnvAllowlist) == 0 {
+		return nil
+	}
+	if e.EnvNamesOnly {
+		names := make([]string, 0, len(e.EnvAllowlist))
+		for _, name := range e.EnvAllowlist {
+			if _, exists := os.LookupEnv(name); exists {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	ret := make(map[string]string, len(e.EnvAllowlist))
+	for _, name := range e.EnvAllowlist {
+		if val, exists := os.LookupEnv(name); exists {
+			ret[name] = val
+		}
+	}
+	return ret
+}
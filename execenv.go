@@ -0,0 +1,74 @@
+package awsexpvar
+
+import (
+	"os"
+	"strings"
+)
+
+// beanstalkConfFile is written on every Elastic Beanstalk instance, which sets no variable of its own in the
+// application environment
+const beanstalkConfFile = "/var/elasticbeanstalk/xray/environment.conf"
+
+// executionEnv is AWS_EXECUTION_ENV when the platform sets it, otherwise a best guess in the same style from the
+// other variables each platform injects, or from the file Elastic Beanstalk leaves on its instances
+func executionEnv() interface{} {
+	if env := os.Getenv("AWS_EXECUTION_ENV"); env != "" {
+		return env
+	}
+	switch {
+	case os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "":
+		return "AWS_Lambda"
+	case os.Getenv("ECS_CONTAINER_METADATA_URI_V4") != "", os.Getenv("ECS_CONTAINER_METADATA_URI") != "":
+		return "AWS_ECS"
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "":
+		return "AWS_ECS"
+	case os.Getenv("ECS_CONTAINER_METADATA_FILE") != "":
+		return "AWS_ECS_EC2"
+	case onBeanstalk(beanstalkConfFile):
+		return "AWS_ElasticBeanstalk"
+	}
+	return nil
+}
+
+// onBeanstalk is true when the Elastic Beanstalk configuration file at path exists
+func onBeanstalk(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// executionEnvName is executionEnv as a string, empty when nothing identifies the platform
+func executionEnvName() string {
+	name, _ := executionEnv().(string)
+	return name
+}
+
+// IsLambda is true inside a Lambda function.  It only reads the environment.
+func IsLambda() bool {
+	return strings.HasPrefix(executionEnvName(), "AWS_Lambda")
+}
+
+// IsECS is true inside an ECS task, on any launch type.  It only reads the environment.
+func IsECS() bool {
+	return strings.HasPrefix(executionEnvName(), "AWS_ECS")
+}
+
+// IsFargate is true inside an ECS task on Fargate.  It only reads the environment.
+func IsFargate() bool {
+	return executionEnvName() == "AWS_ECS_FARGATE"
+}
+
+// IsEC2 is true when IMDS answers with an instance ID, which includes ECS tasks on EC2.  Platforms known to have no
+// IMDS are answered from the environment without a request.  A positive answer is memoized the way InstanceID is,
+// so only the first call, or calls off EC2, wait on IMDS.
+func (e *Expvar) IsEC2() bool {
+	if IsLambda() || withoutIMDS(nil) {
+		return false
+	}
+	id, err := e.InstanceID()
+	return err == nil && id != ""
+}
+
+// IsLocal is true outside of Lambda, ECS and EC2, such as on a developer machine
+func (e *Expvar) IsLocal() bool {
+	return !IsLambda() && !IsECS() && !e.IsEC2()
+}
@@ -0,0 +1,41 @@
+package awsexpvar
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecutionEnv(t *testing.T) {
+	for _, name := range []string{
+		"AWS_EXECUTION_ENV", "AWS_LAMBDA_FUNCTION_NAME", "ECS_CONTAINER_METADATA_URI_V4", "ECS_CONTAINER_METADATA_URI",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "ECS_CONTAINER_METADATA_FILE",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("ECS_CONTAINER_METADATA_FILE", "/var/lib/ecs/data/ecs-container-metadata.json")
+	if env := executionEnv(); env != "AWS_ECS_EC2" {
+		t.Errorf("execution-env %v, want AWS_ECS_EC2 from the metadata file variable", env)
+	}
+	t.Setenv("AWS_EXECUTION_ENV", "AWS_ECS_FARGATE")
+	if env := executionEnv(); env != "AWS_ECS_FARGATE" {
+		t.Errorf("execution-env %v, want AWS_EXECUTION_ENV to win", env)
+	}
+}
+
+func TestOnBeanstalk(t *testing.T) {
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "environment.conf")
+	if onBeanstalk(conf) {
+		t.Error("Beanstalk detected without its configuration file")
+	}
+	if err := ioutil.WriteFile(conf, []byte(`{"deployment_id":1,"version_label":"v1"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !onBeanstalk(conf) {
+		t.Error("Beanstalk not detected from its configuration file")
+	}
+	if onBeanstalk(dir) {
+		t.Error("Beanstalk detected from a directory")
+	}
+}
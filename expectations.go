@@ -0,0 +1,76 @@
+package awsexpvar
+
+import "strings"
+
+// Expectations describe the environment a deployment is meant to land in, typically mounted from the same GitOps
+// repository that defines the deployment.  Empty fields are not checked.
+type Expectations struct {
+	// Cluster is the ECS cluster name or ARN
+	Cluster string `json:"cluster,omitempty"`
+	// Family is the task definition family
+	Family string `json:"family,omitempty"`
+	// InstanceFamily prefixes the instance type, such as "m5" or "c6g."
+	InstanceFamily string `json:"instance-family,omitempty"`
+	Region         string `json:"region,omitempty"`
+	AccountID      string `json:"account-id,omitempty"`
+}
+
+// LoadExpectations reads JSON Expectations from path.  See the yamlconfig module for YAML files.
+func LoadExpectations(path string) (*Expectations, error) {
+	b, err := readJSONFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExpectations(b)
+}
+
+// ParseExpectations is LoadExpectations for expectations that are already in memory
+func ParseExpectations(b []byte) (*Expectations, error) {
+	var ret Expectations
+	if err := decodeStrict(b, &ret); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+type expectationResult struct {
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Pass     bool   `json:"pass"`
+}
+
+// check reports pass or fail for every expectation that is set.  An expectation the metadata cannot answer fails,
+// since a deployment that landed somewhere it cannot see is drift too.  Off EC2, region and account come from the task
+// ARN.
+func (x *Expectations) check(ret map[string]interface{}, task *taskMetadata) interface{} {
+	if x == nil {
+		return nil
+	}
+	results := make(map[string]expectationResult, 5)
+	expect := func(name string, expected string, actual string, pass func() bool) {
+		if expected == "" {
+			return
+		}
+		results[name] = expectationResult{
+			Expected: expected,
+			Actual:   actual,
+			Pass:     actual != "" && pass(),
+		}
+	}
+	cluster := lookupString(ret, "task-metadata", "Cluster")
+	expect("cluster", x.Cluster, cluster, func() bool {
+		return cluster == x.Cluster || strings.HasSuffix(cluster, ":cluster/"+x.Cluster) ||
+			strings.HasSuffix(x.Cluster, ":cluster/"+cluster)
+	})
+	family := lookupString(ret, "task-metadata", "Family")
+	expect("family", x.Family, family, func() bool { return family == x.Family })
+	instanceType := lookupString(ret, "meta-data", "instance-type")
+	expect("instance-family", x.InstanceFamily, instanceType, func() bool {
+		return strings.HasPrefix(instanceType, x.InstanceFamily)
+	})
+	region := detectedRegion(ret["instance-identity"], task)
+	expect("region", x.Region, region, func() bool { return region == x.Region })
+	accountID := detectedAccount(ret["instance-identity"], task)
+	expect("account-id", x.AccountID, accountID, func() bool { return accountID == x.AccountID })
+	return results
+}
@@ -0,0 +1,42 @@
+package awsexpvar
+
+import "testing"
+
+func TestExpectations(t *testing.T) {
+	x, err := ParseExpectations([]byte(`{"cluster": "default", "family": "web", "instance-family": "c6g",
+		"region": "us-west-2", "account-id": "123456789012"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := map[string]interface{}{
+		"task-metadata":     map[string]interface{}{"Cluster": "arn:aws:ecs:us-west-2:123456789012:cluster/default"},
+		"meta-data":         map[string]interface{}{"instance-type": "m5.large"},
+		"instance-identity": map[string]string{"region": "us-west-2", "accountId": "123456789012"},
+	}
+	results, _ := x.check(ret, nil).(map[string]expectationResult)
+	for name, pass := range map[string]bool{
+		"cluster":         true,
+		"family":          false,
+		"instance-family": false,
+		"region":          true,
+		"account-id":      true,
+	} {
+		if results[name].Pass != pass {
+			t.Errorf("%s: %+v, want pass %v", name, results[name], pass)
+		}
+	}
+	if _, err := ParseExpectations([]byte(`{"regions": "us-west-2"}`)); err == nil {
+		t.Error("unknown key accepted")
+	}
+}
+
+func TestExpectationsWithoutIdentity(t *testing.T) {
+	x := &Expectations{Region: "us-west-2", AccountID: "123456789012"}
+	task := &taskMetadata{TaskARN: "arn:aws:ecs:us-west-2:123456789012:task/default/0123456789abcdef"}
+	results, _ := x.check(map[string]interface{}{}, task).(map[string]expectationResult)
+	for _, name := range []string{"region", "account-id"} {
+		if !results[name].Pass {
+			t.Errorf("%s: %+v, want a pass from the task ARN", name, results[name])
+		}
+	}
+}
@@ -2,20 +2,40 @@ package awsexpvar
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"expvar"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
-const metadataURL = "http://169.254.169.254/latest/meta-data/"
-const taskRoleURL = "http://169.254.170.2"
-const instanceIdentURL = "http://169.254.169.254/latest/dynamic/instance-identity/document"
-const userDataURL = "http://169.254.169.254/latest/user-data"
+// Paths are relative to each of MetadataEndpoints
+const metadataPath = "/latest/meta-data/"
+const instanceIdentPath = "/latest/dynamic/instance-identity/document"
+const userDataPath = "/latest/user-data"
+
+// ErrNotFound is returned for metadata paths that do not exist.  IMDSClient implementations return it too.
+var ErrNotFound = errors.New("not found")
+
+// version is reported in the default User-Agent
+const version = "0.2"
+
+// DefaultTimeout bounds each metadata request when Timeout is zero
+const DefaultTimeout = 200 * time.Millisecond
+
+// DefaultWalkConcurrency is how many requests one fetch has in flight when WalkConcurrency is zero
+const DefaultWalkConcurrency = 8
+
+// DefaultMaxDepth is how many directories deep the meta-data crawl goes when MaxDepth is zero.  The deepest paths IMDS
+// documents today, under network/interfaces/macs/, are five deep.
+const DefaultMaxDepth = 10
 
 // Logger is optional and allows logging errors closing local request bodies
 type Logger interface {
@@ -26,32 +46,338 @@ type Logger interface {
 type Expvar struct {
 	Log    Logger
 	Client *http.Client
+	// TLSConfig is used when Client is nil, for environments that front IMDS with an HTTPS proxy (see HTTPS_PROXY)
+	// signed by an internal CA or requiring client certificates
+	TLSConfig *tls.Config
+	// CredentialsCABundle is a PEM file of roots trusted when AWS_CONTAINER_CREDENTIALS_FULL_URI is an https URL.
+	// Defaults to AWS_CA_BUNDLE, like the SDKs.
+	CredentialsCABundle string
+	// CredentialsInsecureSkipVerify disables certificate verification of the full credentials URI.  Only for dev.
+	CredentialsInsecureSkipVerify bool
+	// DisableIMDSv1Fallback fails IMDS requests that could not get an IMDSv2 token, instead of retrying them as
+	// IMDSv1.  Either way "imds_mode" says how IMDS was reached.
+	DisableIMDSv1Fallback bool
+	// Timeout bounds each request, body included.  Defaults to DefaultTimeout, which can be too tight behind some
+	// VPC or Docker network setups.
+	Timeout time.Duration
+	// UserAgent is sent on every request.  Defaults to "awsexpvar/<version> (+<program name>)" so metadata proxy
+	// operators can tell this traffic apart from SDK traffic.
+	UserAgent string
+	// MetadataEndpoints are tried in order until one answers, for environments that define their own resolution
+	// chain: the IPv6 IMDS endpoint, a local mock, or a file:// directory laid out like IMDS.  Defaults to
+	// DefaultMetadataEndpoints.
+	MetadataEndpoints []string
+	// SecretEnvVars are the names of environment variables the task definition injects from Secrets Manager or SSM.
+	// Their values are never exposed, only whether each was set, under "secret-env".
+	SecretEnvVars []string
+	// EnvAllowlist are environment variables, such as SERVICE_NAME or DEPLOY_ID, exposed under "env" next to the AWS
+	// metadata.  Nothing outside the list is exposed.
+	EnvAllowlist []string
+	// HumanSizes adds, next to every memory limit under "limits", the limit in bytes and in human readable form such
+	// as "512MiB", for whoever reads the output rather than graphs it
+	HumanSizes bool
+	// LabelAllowlist are Docker labels, such as "git-sha" or "com.amazonaws.ecs.*", exposed for each container under
+	// "container-labels".  A trailing "*" matches every label with that prefix.  Nothing outside the list is exposed.
+	LabelAllowlist []string
+	// EnvNamesOnly exposes which allowlisted variables are set, without their values
+	EnvNamesOnly bool
+	// OnIdentityChange is called when the instance ID or task ARN differs from the previous fetch
+	OnIdentityChange func(IdentityChange)
+	// TagFallback, if set, supplies "instance-tags" when the IMDS tags endpoint is not enabled
+	TagFallback InstanceTagger
+	// TaskEnrichment, if set, adds ECS API data about the task under "task-enrichment"
+	TaskEnrichment TaskDescriber
+	// FileCipher, if set, encrypts every snapshot this package writes to disk
+	FileCipher SnapshotCipher
+	// IMDS, if set, serves every IMDS request instead of MetadataEndpoints, so a client the process already has
+	// shares its token, transport and retries with this package.  Its timeouts apply instead of this package's.
+	IMDS IMDSClient
+	// TaskStats adds the Docker stats of every container in the task (CPU, memory, network) under "task-stats", so
+	// one scrape has both identity and resource usage.  Off by default, since stats are large.
+	TaskStats bool
+	// Expectations, if set, are checked on every fetch and reported pass or fail under "expectations"
+	Expectations *Expectations
+	// MaxRequestsPerRefresh caps the HTTP requests one fetch may make, since IMDS trees of instances with many ENIs
+	// run to dozens of requests.  Requests past the cap fail, and the output is marked with "truncated_walk".  Zero
+	// means no cap.
+	MaxRequestsPerRefresh int
+	// CacheTTL, when there is no snapshot from Refresh, lets Var reuse a fetch for this long, so frequent scrapes
+	// such as Prometheus' do not crawl IMDS every time.  Zero crawls on every evaluation.
+	CacheTTL time.Duration
+	// WalkTimeout bounds one whole fetch, so a slow IMDS cannot hang the expvar handler for dozens of request
+	// timeouts.  Requests past the deadline fail, and what was collected so far is published marked with
+	// "truncated_walk".  Zero means no bound beyond Timeout per request.
+	WalkTimeout time.Duration
+	// WalkConcurrency caps the requests one fetch has in flight while it crawls the metadata tree, which for
+	// instances with many ENIs resolves in a fraction of the time of one request at a time.  Defaults to
+	// DefaultWalkConcurrency; one crawls sequentially.
+	WalkConcurrency int
+	// MaxDepth bounds how many directories deep the meta-data crawl goes, so an endpoint that answers with directories
+	// forever cannot run away with it.  Directories past it are left out with an error and the output is marked with
+	// "truncated_walk".  Zero means DefaultMaxDepth.
+	MaxDepth int
+	// LogRedactions logs, through Log and never in the published output, every value each fetch left out and why, so
+	// the redaction rules can be audited
+	LogRedactions bool
+	// RedactKeys are key names, such as "Signature", whose values are removed wherever they appear in the output,
+	// matched case-insensitively.  They add to DefaultRedactKeys.
+	RedactKeys []string
+	// CrawlAllowlist restricts the meta-data crawl to these paths, such as "placement/" and "instance-id", and what is
+	// under them.  Every path is crawled when it is empty.
+	CrawlAllowlist []string
+	// CrawlDenylist are meta-data paths, such as "public-keys/", that are not crawled, even under CrawlAllowlist.  Both
+	// lists cut IMDS requests as well as output.
+	CrawlDenylist []string
+	// ScrubPatterns are replaced wherever they match a string of the output, user-data included.  Nothing is scrubbed
+	// unless they are set; DefaultScrubPatterns is a starting point.
+	ScrubPatterns []*regexp.Regexp
+	// DisabledSections are top level keys that are neither fetched nor exposed.  See LoadConfig.
+	DisabledSections []string
+	// RefreshInterval is how often Start refreshes the snapshot
+	RefreshInterval time.Duration
+
+	mu        sync.Mutex
+	cached    *cachedSnapshot
+	ttlCached *cachedSnapshot
+
+	activeEndpoint  int
+	agentURL        string
+	lastIdentity    map[string]string
+	identityChanges []IdentityChange
+	// sectionOverrides are sections enabled (true) or disabled (false) at runtime, over DisabledSections
+	sectionOverrides map[string]bool
+	clock            *clockSkew
+	imdsMode         string
+	imdsTokens       map[string]imdsToken
+	tokenRequests    map[string]*tokenFlight
+	transforms       []transform
+	proxied          map[string]proxiedLeaf
+	inFlight         *flight
+	metadataProxy    *metadataProxy
+	// lastLeaves are the leaves of the previous fetch by section, which history is the difference of
+	lastLeaves map[string]map[string]string
+	history    []historyEvent
+	// background, intervalOverride and nextRefresh are the state of Start
+	background       *background
+	intervalOverride time.Duration
+	nextRefresh      time.Time
+
+	stats stats
+
+	// onceMu guards onceValues and onceFlights, and is never held while FetchOnce waits on IMDS
+	onceMu      sync.Mutex
+	onceValues  map[Field]string
+	onceFlights map[Field]*fieldFlight
+
+	clientOnce    sync.Once
+	defaultClient *http.Client
+	credClient    *http.Client
+	credClientErr error
 }
 
 func (e *Expvar) client() *http.Client {
-	if e.Client == nil {
+	if e.Client != nil {
+		return e.Client
+	}
+	if e.TLSConfig == nil {
 		return http.DefaultClient
 	}
-	return e.Client
+	e.clientOnce.Do(func() {
+		e.defaultClient = newHTTPClient(e.TLSConfig)
+	})
+	return e.defaultClient
+}
+
+// newHTTPClient is how the package builds clients when it needs TLS settings http.DefaultClient does not have
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			TLSClientConfig:       tlsConfig,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+func (e *Expvar) timeout() time.Duration {
+	if e.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return e.Timeout
+}
+
+func (e *Expvar) walkConcurrency() int {
+	if e.WalkConcurrency <= 0 {
+		return DefaultWalkConcurrency
+	}
+	return e.WalkConcurrency
 }
 
-type availableCommandResponse struct {
-	AvailableCommands []string `json:"AvailableCommands"`
+func (e *Expvar) maxDepth() int {
+	if e.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return e.MaxDepth
+}
+
+func (e *Expvar) userAgent() string {
+	if e.UserAgent != "" {
+		return e.UserAgent
+	}
+	return "awsexpvar/" + version + " (+" + filepath.Base(os.Args[0]) + ")"
 }
 
-// Var creates the expvar you should expose
+// Var creates the expvar you should expose.  Once Refresh has been called, it serves the refreshed snapshot
+// rather than crawling the metadata endpoints on every evaluation.
 func (e *Expvar) Var() expvar.Var {
 	return expvar.Func(func() interface{} {
-		ret := make(map[string]interface{}, 5)
-		ret["meta-data"] = e.metaData()
-		ret["ecs-metadata"] = e.ecs()
-		ret["instance-identity"] = e.instanceIdentity()
-		ret["user-data"] = e.userData()
-		ret["container-metadata"] = e.containerMetadata()
-		return filterNil(ret)
+		return e.published(context.Background())
 	})
 }
 
+func (e *Expvar) fetch(ctx context.Context) map[string]interface{} {
+	if e.WalkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.WalkTimeout)
+		defer cancel()
+	}
+	w := &walk{
+		maxRequests: int64(e.MaxRequestsPerRefresh),
+		inFlight:    make(chan struct{}, e.walkConcurrency()),
+	}
+	ctx = withWalk(ctx, w)
+	ret := make(map[string]interface{}, 22)
+	sections := make(sectionStatuses, 6)
+	var task *taskMetadata
+	var rawTask map[string]interface{}
+	err := errDisabled
+	if !e.sectionDisabled("task-metadata") {
+		task, rawTask, err = e.taskMetadata(ctx)
+	}
+	sections.record(ret, "task-metadata", rawTask, err)
+	taskStats, err := e.source(ctx, "task-stats", e.taskStats)
+	sections.record(ret, "task-stats", taskStats, err)
+	// Fargate and ECS Anywhere have no IMDS, so asking only burns the request timeout
+	w.skipIMDS = withoutIMDS(task)
+	// the remaining sources do not depend on each other, so a fetch takes as long as the slowest of them
+	fetched := e.sources(ctx, []namedSource{
+		{name: "meta-data", fetch: e.metaData},
+		{name: "ecs-metadata", fetch: func(ctx context.Context) (interface{}, error) {
+			return e.ecs(ctx, task)
+		}},
+		{name: "instance-identity", fetch: e.instanceIdentity},
+		{name: "user-data", fetch: e.userData},
+		{name: "container-metadata", fetch: e.containerMetadata},
+	})
+	for _, f := range fetched {
+		sections.record(ret, f.name, f.val, f.err)
+	}
+	ret["public-ip"] = publicIP(ret["meta-data"])
+	ret["interfaces"] = interfaces(ret["meta-data"])
+	for name, count := range networkCounts(ret["meta-data"]) {
+		ret[name] = count
+	}
+	ret["region-warning"] = regionMismatch(ret["instance-identity"])
+	ret["instance-tags"] = e.instanceTags(ctx, ret["meta-data"], ret["instance-identity"])
+	if task != nil {
+		ret["limits"] = task.limits(e.HumanSizes)
+		ret["container-images"] = task.images()
+		ret["container-status"] = task.statuses()
+		ret["service-connect"] = e.serviceConnect(task)
+		ret["volumes"] = task.volumes()
+		ret["image-age"] = task.imageAges()
+		ret["container-labels"] = task.labels(e.LabelAllowlist)
+		ret["cgroups"] = cgroupPaths(task)
+		ret["task-enrichment"] = e.taskEnrichment(ctx, task)
+	}
+	ret["execution-env"] = executionEnv()
+	ret["secret-env"] = e.secretEnv()
+	for _, name := range e.SecretEnvVars {
+		noteRedaction(ctx, "secret-env/"+name, "listed in SecretEnvVars")
+	}
+	ret["env"] = e.allowedEnv()
+	if e.EnvNamesOnly {
+		for _, name := range e.EnvAllowlist {
+			noteRedaction(ctx, "env/"+name, "EnvNamesOnly")
+		}
+	}
+	ret["uptime"] = computeUptime(time.Now(), ret["instance-identity"], task)
+	ret["filesystem"] = filesystem()
+	ret["clock-skew"] = e.clockSkew()
+	ret["imds_mode"] = e.imdsModeOf()
+	ret["metadata_proxy"] = e.metadataProxyOf()
+	ret["sts"] = stsEndpoints(ret["instance-identity"], task)
+	ret["endpoints"] = endpoints(ret["instance-identity"], task)
+	ret["credential-source"] = credentialSourceInfo(ret["meta-data"], func() string {
+		// the agent section has it already, unless the task metadata endpoint was used instead
+		if role := lookupString(ret["ecs-metadata"], "RoleArn"); role != "" {
+			return role
+		}
+		return e.taskRole(withSource(ctx, sourceCredentials))
+	})
+	ret["arns"] = arns(ret, task)
+	ret["expectations"] = e.Expectations.check(ret, task)
+	ret["container-runtime"] = containerRuntimeInfo(ret["ecs-metadata"], task)
+	ret["capabilities"] = e.capabilities(ret)
+	ret["_sections"] = sections
+	e.redactKeys(ctx, ret)
+	e.scrub(ctx, ret)
+	e.recordRedactions(w)
+	ret["_stats"] = e.stats.export()
+	if w.wasTruncated() || ctx.Err() != nil {
+		ret["truncated_walk"] = true
+	}
+	for name := range ret {
+		if e.sectionDisabled(name) {
+			delete(ret, name)
+		}
+	}
+	ret = filterNil(ret)
+	e.applyTransforms(ret)
+	e.logRedactions(w)
+	e.detectIdentityChange(ret)
+	e.recordHistory(ret)
+	return ret
+}
+
+// source skips fetching sections that are disabled
+func (e *Expvar) source(ctx context.Context, name string, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	if e.sectionDisabled(name) {
+		return nil, errDisabled
+	}
+	return fetch(ctx)
+}
+
+type namedSource struct {
+	name  string
+	fetch func(context.Context) (interface{}, error)
+}
+
+type sourceResult struct {
+	name string
+	val  interface{}
+	err  error
+}
+
+// sources fetches each of srcs concurrently, through source, and returns their results in the same order
+func (e *Expvar) sources(ctx context.Context, srcs []namedSource) []sourceResult {
+	ret := make([]sourceResult, len(srcs))
+	var wg sync.WaitGroup
+	for i := range srcs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			val, err := e.source(ctx, srcs[i].name, srcs[i].fetch)
+			ret[i] = sourceResult{name: srcs[i].name, val: val, err: err}
+		}(i)
+	}
+	wg.Wait()
+	return ret
+}
+
 func filterNil(r map[string]interface{}) map[string]interface{} {
 	ret := make(map[string]interface{}, len(r))
 	for k, v := range r {
@@ -63,60 +389,62 @@ func filterNil(r map[string]interface{}) map[string]interface{} {
 	return ret
 }
 
-func (e *Expvar) containerMetadata() interface{} {
+func (e *Expvar) containerMetadata(_ context.Context) (interface{}, error) {
 	metadataFile := os.Getenv("ECS_CONTAINER_METADATA_FILE")
 	if metadataFile == "" {
-		return nil
+		return nil, errNotApplicable
 	}
 	fileBytes, err := ioutil.ReadFile(metadataFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	asObj := make(map[string]interface{}, 5)
-	if err := json.Unmarshal(fileBytes, &asObj); err != nil {
-		return err
+	if err := decodeJSON(fileBytes, &asObj); err != nil {
+		return nil, err
 	}
-	return asObj
+	return asObj, nil
 }
 
-func (e *Expvar) userData() interface{} {
-	val, err := e.single(userDataURL)
-	if err != nil {
-		return nil
-	}
-	return val
+func (e *Expvar) userData(ctx context.Context) (interface{}, error) {
+	return e.single(ctx, userDataPath)
 }
 
-func (e *Expvar) instanceIdentity() interface{} {
-	val, err := e.single(instanceIdentURL)
+// instanceIdentity decodes the document once, keeping fields such as billingProducts that are arrays, and every
+// section that reads the region or account reads this map
+func (e *Expvar) instanceIdentity(ctx context.Context) (interface{}, error) {
+	b, err := e.get(ctx, instanceIdentPath)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return val
+	doc := make(map[string]interface{})
+	if err := decodeJSON(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
-func (e *Expvar) metaData() interface{} {
-	val, err := e.recurse(metadataURL)
-	if err != nil {
-		return nil
-	}
-	return val
+func (e *Expvar) metaData(ctx context.Context) (interface{}, error) {
+	return e.recurse(ctx, metadataPath)
 }
 
-func (e *Expvar) ecs() interface{} {
-	ecsURL := e.ecsURL()
+// ecs comes from the task metadata endpoint when it answered, which is preferred over agent introspection: v4, then
+// v3, then the agent.  It is not applicable when there is no local IP to find the agent on.
+func (e *Expvar) ecs(ctx context.Context, task *taskMetadata) (interface{}, error) {
+	if task != nil {
+		return task.ecsSection(), nil
+	}
+	ecsURL := e.ecsURL(ctx)
 	if ecsURL == "" {
-		return nil
+		return nil, errNotApplicable
 	}
-	val, err := e.recurse(ecsURL)
+	val, err := e.agentIntrospection(withSource(ctx, sourceECSAgent), ecsURL)
 	if err != nil {
-		return err
-	}
-	if asMap, ok := val.(map[string]interface{}); ok {
-		taskRole := e.taskRole()
-		asMap["RoleArn"] = taskRole
+		e.invalidateECSURL()
+		return nil, err
 	}
-	return val
+	val["RoleArn"] = e.taskRole(withSource(ctx, sourceCredentials))
+	val["Source"] = ecsSourceAgent
+	return val, nil
 }
 
 type metadataTask struct {
@@ -138,52 +466,63 @@ type tasksEndpoint struct {
 	Tasks []metadataTask
 }
 
-func (e *Expvar) httpGet(base string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", base, nil)
-	if err != nil {
-		return nil, err
-	}
+func (e *Expvar) getBody(ctx context.Context, base string) ([]byte, error) {
+	return e.getBodyWith(ctx, e.client(), base, nil)
+}
 
-	ctx, onDone := context.WithTimeout(context.Background(), time.Millisecond*200)
-	defer onDone()
-	req = req.WithContext(ctx)
-	return e.client().Do(req)
+func (e *Expvar) getBodyWith(ctx context.Context, client *http.Client, base string, header http.Header) ([]byte, error) {
+	b, _, err := e.do(ctx, client, http.MethodGet, base, header)
+	return b, err
 }
 
-func (e *Expvar) taskRole() string {
-	credURL := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI")
-	if credURL == "" {
-		return "(no-relative-url-for-task-information)"
+// do reads the whole body inside the per request timeout, so the timeout covers the body as well as the headers
+func (e *Expvar) do(ctx context.Context, client *http.Client, method string, base string, header http.Header) ([]byte, int, error) {
+	if !takeRequest(ctx) {
+		return nil, 0, errRequestBudget
 	}
-	singleVal, err := e.single(taskRoleURL + credURL)
+	ctx, onDone := context.WithTimeout(ctx, e.timeout())
+	defer onDone()
+	req, err := http.NewRequest(method, base, nil)
 	if err != nil {
-		return err.Error()
+		return nil, 0, err
 	}
-	if asMap, ok := singleVal.(map[string]string); ok {
-		return asMap["RoleArn"]
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
 	}
-	return "<invalid_single_value>"
-}
-
-func (e *Expvar) single(base string) (interface{}, error) {
-	resp, err := e.httpGet(base)
+	req.Header.Set("User-Agent", e.userAgent())
+	start := time.Now()
+	defer func() {
+		e.stats.recordLatency(sourceFrom(ctx), time.Since(start))
+	}()
+	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer e.closeBody(resp)
+	if sourceFrom(ctx) == sourceIMDS {
+		e.recordDate(resp.Header, start, time.Now())
+		e.recordProxy(method, base, resp.StatusCode, resp.Header)
+	}
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, errors.New("not found")
+		return nil, resp.StatusCode, ErrNotFound
 	}
-	var b []byte
-	if b, err = ioutil.ReadAll(resp.Body); err != nil {
+	if err := classifyStatus(sourceFrom(ctx), resp.StatusCode); err != nil {
+		return nil, resp.StatusCode, err
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	return b, resp.StatusCode, err
+}
+
+func (e *Expvar) single(ctx context.Context, base string) (interface{}, error) {
+	b, err := e.get(ctx, base)
+	if err != nil {
 		return nil, err
 	}
 	respBody := string(b)
 	m := map[string]string{}
 	if err := json.Unmarshal([]byte(respBody), &m); err == nil {
-		clearOut(m, "Token")
-		clearOut(m, "AccessKeyId")
-		clearOut(m, "SecretAccessKey")
+		// credential fields are removed from the whole fetch by redactKeys
 		return m, nil
 	}
 	t := tasksEndpoint{}
@@ -193,96 +532,94 @@ func (e *Expvar) single(base string) (interface{}, error) {
 	return respBody, nil
 }
 
-func clearOut(m map[string]string, key string) {
-	if _, exists := m[key]; exists {
-		m[key] = "(removed)"
-	}
-}
-
-func (e *Expvar) recurse(base string) (interface{}, error) {
+func (e *Expvar) recurse(ctx context.Context, base string) (interface{}, error) {
 	ret := make(map[string]interface{})
-	resp, err := e.httpGet(base)
+	b, err := e.get(ctx, base)
 	if err != nil {
 		return nil, err
 	}
-	defer e.closeBody(resp)
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, errors.New("not found")
-	}
-	var b []byte
-	if b, err = ioutil.ReadAll(resp.Body); err != nil {
-		return nil, err
-	}
 	respBody := string(b)
-	// Try availableCommandResponse for sub commands
-	var m availableCommandResponse
-	if err := json.Unmarshal([]byte(respBody), &m); err == nil {
-		if len(m.AvailableCommands) > 0 {
-			for _, subCommand := range m.AvailableCommands {
-				if subCommand == "/license" {
-					continue
-				}
-				val, err := e.single(base + subCommand)
-				if err != nil {
-					ret[subCommand] = err
-				} else {
-					ret[subCommand] = val
-				}
-			}
-			return ret, nil
-		}
-	}
 	// Got an object back.  Is it a link to more sub directories, or is it the end.  We don't know.
 	parts := strings.Split(respBody, "\n")
-	e.processParts(base, parts, ret)
+	e.processParts(ctx, base, parts, ret)
 	return ret, nil
 }
 
-func (e *Expvar) processParts(base string, parts []string, ret map[string]interface{}) {
+// processParts fetches every part of a directory concurrently.  The walk's concurrency limit is only held for each
+// request, never while waiting on children, so deep trees cannot deadlock it.
+func (e *Expvar) processParts(ctx context.Context, base string, parts []string, ret map[string]interface{}) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 	for _, part := range parts {
 		if part == "" {
 			continue
 		}
 		if part == "security-credentials/" {
+			noteRedaction(ctx, base+"/"+part, "instance role credentials are never crawled")
 			continue
 		}
-		if !strings.HasSuffix(part, "/") {
-			val, err := e.single(base + "/" + part)
+		if !e.crawled(base + "/" + part) {
+			continue
+		}
+		if strings.HasSuffix(part, "/") && len(crawlSegments(base+"/"+part)) > e.maxDepth() {
+			truncate(ctx)
+			ret[part] = errMaxDepth
+			continue
+		}
+		wg.Add(1)
+		go func(part string) {
+			defer wg.Done()
+			var val interface{}
+			var err error
+			if strings.HasSuffix(part, "/") {
+				val, err = e.recurse(ctx, base+"/"+part)
+			} else {
+				val, err = e.single(ctx, base+"/"+part)
+			}
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
 				ret[part] = err
 			} else {
 				ret[part] = val
 			}
-			continue
-		}
-		val, err := e.recurse(base + "/" + part)
-		if err != nil {
-			ret[part] = err
-		} else {
-			ret[part] = val
-		}
+		}(part)
 	}
+	wg.Wait()
 }
 
-func (e *Expvar) localIP() string {
-	resp, err := e.httpGet("http://169.254.169.254/latest/meta-data/local-ipv4/")
-	if err != nil {
-		return ""
-	}
-	defer e.closeBody(resp)
-	localIP, err := ioutil.ReadAll(resp.Body)
+func (e *Expvar) localIP(ctx context.Context) string {
+	localIP, err := e.imdsGet(ctx, metadataPath+"local-ipv4")
 	if err != nil {
 		return ""
 	}
 	return string(localIP)
 }
 
-func (e *Expvar) ecsURL() string {
-	ip := e.localIP()
+// ecsURL is cached: the instance IP cannot change during the life of the process, so it only needs to be looked
+// up again after the agent stops answering on it
+func (e *Expvar) ecsURL(ctx context.Context) string {
+	e.mu.Lock()
+	cached := e.agentURL
+	e.mu.Unlock()
+	if cached != "" {
+		return cached
+	}
+	ip := e.localIP(ctx)
 	if ip == "" {
 		return ""
 	}
-	return "http://" + ip + ":51678"
+	agentURL := "http://" + ip + ":51678"
+	e.mu.Lock()
+	e.agentURL = agentURL
+	e.mu.Unlock()
+	return agentURL
+}
+
+func (e *Expvar) invalidateECSURL() {
+	e.mu.Lock()
+	e.agentURL = ""
+	e.mu.Unlock()
 }
 
 func (e *Expvar) closeBody(resp *http.Response) {
@@ -0,0 +1,138 @@
+package awsexpvar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Field is a well known metadata item that Fetch can resolve on its own
+type Field int
+
+// Fields understood by Fetch
+const (
+	FieldInstanceID Field = iota + 1
+	FieldInstanceType
+	FieldAMIID
+	FieldAvailabilityZone
+	FieldRegion
+	FieldAccountID
+	FieldLocalIPv4
+	FieldLocalHostname
+	FieldPublicIPv4
+	FieldTaskARN
+	FieldCluster
+	FieldTaskFamily
+	FieldTaskRevision
+)
+
+// fieldInfo says where a field comes from.  Exactly one of metaPath, identity or task is set.
+type fieldInfo struct {
+	name     string
+	metaPath string
+	identity func(*identityDocument) string
+	task     func(*taskMetadata) string
+}
+
+var fieldInfos = map[Field]fieldInfo{
+	FieldInstanceID:       {name: "instance-id", metaPath: "instance-id"},
+	FieldInstanceType:     {name: "instance-type", metaPath: "instance-type"},
+	FieldAMIID:            {name: "ami-id", metaPath: "ami-id"},
+	FieldAvailabilityZone: {name: "availability-zone", metaPath: "placement/availability-zone"},
+	FieldRegion:           {name: "region", identity: func(d *identityDocument) string { return d.Region }},
+	FieldAccountID:        {name: "account-id", identity: func(d *identityDocument) string { return d.AccountID }},
+	FieldLocalIPv4:        {name: "local-ipv4", metaPath: "local-ipv4"},
+	FieldLocalHostname:    {name: "local-hostname", metaPath: "local-hostname"},
+	FieldPublicIPv4:       {name: "public-ipv4", metaPath: "public-ipv4"},
+	FieldTaskARN:          {name: "task-arn", task: func(t *taskMetadata) string { return t.TaskARN }},
+	FieldCluster:          {name: "cluster", task: func(t *taskMetadata) string { return t.Cluster }},
+	FieldTaskFamily:       {name: "task-family", task: func(t *taskMetadata) string { return t.Family }},
+	FieldTaskRevision:     {name: "task-revision", task: func(t *taskMetadata) string { return t.Revision }},
+}
+
+func (f Field) String() string {
+	if info, exists := fieldInfos[f]; exists {
+		return info.name
+	}
+	return fmt.Sprintf("Field(%d)", int(f))
+}
+
+// ParseField is the inverse of Field.String
+func ParseField(name string) (Field, error) {
+	for f, info := range fieldInfos {
+		if info.name == name {
+			return f, nil
+		}
+	}
+	return 0, errors.New("unknown field " + name)
+}
+
+// Fetch resolves only the requested fields, making the fewest requests it can: one per meta-data path, and at most
+// one each for the identity document and the task metadata however many fields they supply.  Fields that could
+// not be resolved are missing from the result and described by the returned error.
+func (e *Expvar) Fetch(ctx context.Context, fields ...Field) (map[Field]string, error) {
+	ret := make(map[Field]string, len(fields))
+	f := fieldFetcher{e: e}
+	var failures []string
+	for _, field := range fields {
+		if _, exists := ret[field]; exists {
+			continue
+		}
+		val, err := f.fetch(ctx, field)
+		if err != nil {
+			failures = append(failures, field.String()+": "+err.Error())
+			continue
+		}
+		ret[field] = val
+	}
+	if len(failures) > 0 {
+		return ret, errors.New(strings.Join(failures, "; "))
+	}
+	return ret, nil
+}
+
+// fieldFetcher remembers the identity document and task metadata for the duration of one Fetch
+type fieldFetcher struct {
+	e           *Expvar
+	identity    *identityDocument
+	identityErr error
+	task        *taskMetadata
+	taskErr     error
+}
+
+func (f *fieldFetcher) fetch(ctx context.Context, field Field) (string, error) {
+	info, exists := fieldInfos[field]
+	if !exists {
+		return "", errors.New("unknown field")
+	}
+	var val string
+	switch {
+	case info.metaPath != "":
+		b, err := f.e.imdsGet(ctx, metadataPath+info.metaPath)
+		if err != nil {
+			return "", err
+		}
+		val = strings.TrimSpace(string(b))
+	case info.identity != nil:
+		if f.identity == nil && f.identityErr == nil {
+			f.identity, f.identityErr = f.e.identityDocument(ctx)
+		}
+		if f.identityErr != nil {
+			return "", f.identityErr
+		}
+		val = info.identity(f.identity)
+	case info.task != nil:
+		if f.task == nil && f.taskErr == nil {
+			f.task, f.taskErr = f.e.parsedTaskMetadata(ctx)
+		}
+		if f.taskErr != nil {
+			return "", f.taskErr
+		}
+		val = info.task(f.task)
+	}
+	if val == "" {
+		return "", ErrNotFound
+	}
+	return val, nil
+}
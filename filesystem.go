@@ -0,0 +1,49 @@
+package awsexpvar
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// FileWritesOK and FileWritesDegraded are the "file-writes" values of the filesystem section.  Degraded means features
+// that write files, such as DumpOnPanicToFile, fall back to stderr.
+const (
+	FileWritesOK       = "ok"
+	FileWritesDegraded = "degraded"
+)
+
+type filesystemStatus struct {
+	RootReadOnly    *bool  `json:"root-read-only,omitempty"`
+	ScratchDir      string `json:"scratch-dir"`
+	ScratchWritable bool   `json:"scratch-writable"`
+	FileWrites      string `json:"file-writes"`
+}
+
+// filesystem reports whether the root filesystem is mounted read-only, which hardened ECS tasks
+// (readonlyRootFilesystem) do, and whether there is somewhere left to write scratch files
+func filesystem() filesystemStatus {
+	ret := filesystemStatus{
+		ScratchDir: os.TempDir(),
+		FileWrites: FileWritesOK,
+	}
+	if readOnly, err := rootReadOnly(); err == nil {
+		ret.RootReadOnly = &readOnly
+	}
+	ret.ScratchWritable = dirWritable(ret.ScratchDir)
+	if !ret.ScratchWritable {
+		ret.FileWrites = FileWritesDegraded
+	}
+	return ret
+}
+
+// dirWritable creates and removes a file in dir, since permissions and mount flags alone miss things like full disks
+func dirWritable(dir string) bool {
+	f, err := ioutil.TempFile(dir, ".awsexpvar-")
+	if err != nil {
+		return false
+	}
+	name := f.Name()
+	closeErr := f.Close()
+	removeErr := os.Remove(name)
+	return closeErr == nil && removeErr == nil
+}
@@ -0,0 +1,15 @@
+//go:build linux
+// +build linux
+
+package awsexpvar
+
+import "syscall"
+
+func rootReadOnly() (bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs("/", &st); err != nil {
+		return false, err
+	}
+	// ST_RDONLY shares its value with MS_RDONLY
+	return st.Flags&syscall.MS_RDONLY != 0, nil
+}
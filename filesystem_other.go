@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package awsexpvar
+
+// rootReadOnly is only implemented on linux.  Elsewhere the filesystem section reports just the scratch dir.
+func rootReadOnly() (bool, error) {
+	return false, errNotApplicable
+}
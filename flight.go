@@ -0,0 +1,43 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// flight is one fetch that concurrent evaluations wait on instead of crawling again
+type flight struct {
+	done    chan struct{}
+	encoded json.RawMessage
+	err     error
+}
+
+// sharedFetch is fetch, encoded, with concurrent callers sharing the one in flight, so two scrapers hitting
+// /debug/vars together cost one crawl and get the same snapshot.  It is shared as JSON so each caller can decode a
+// copy of its own.  The crawl runs on a context of its own, bounded by WalkTimeout, so the caller that started it
+// giving up does not cut it short for the others; each caller only waits as long as its own ctx allows.
+func (e *Expvar) sharedFetch(ctx context.Context) (json.RawMessage, error) {
+	e.mu.Lock()
+	f := e.inFlight
+	if f == nil {
+		f = &flight{done: make(chan struct{})}
+		e.inFlight = f
+		go e.fly(f)
+	}
+	e.mu.Unlock()
+	select {
+	case <-f.done:
+		return f.encoded, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fly runs the fetch of f, detached from the callers waiting on it
+func (e *Expvar) fly(f *flight) {
+	f.encoded, f.err = json.Marshal(e.fetch(context.Background()))
+	e.mu.Lock()
+	e.inFlight = nil
+	e.mu.Unlock()
+	close(f.done)
+}
@@ -0,0 +1,91 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSharedFetchDetached(t *testing.T) {
+	var requests int64
+	started := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/task" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt64(&requests, 1)
+		started <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(testTask))
+	}))
+	defer srv.Close()
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", srv.URL)
+	e := &Expvar{Client: testClient(), Timeout: time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	first := make(chan error, 1)
+	go func() {
+		_, err := e.sharedFetch(ctx)
+		first <- err
+	}()
+	<-started
+	encoded, err := e.sharedFetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-first; err != context.DeadlineExceeded {
+		t.Errorf("caller that ran out of time: %v, want its own deadline", err)
+	}
+	var ret map[string]interface{}
+	if err := json.Unmarshal(encoded, &ret); err != nil {
+		t.Fatal(err)
+	}
+	if task, _ := ret["task-metadata"].(map[string]interface{}); task["Family"] != "web" {
+		t.Errorf("task-metadata %v, want the crawl finished despite the first caller giving up", ret["task-metadata"])
+	}
+	if ret["truncated_walk"] != nil {
+		t.Errorf("truncated_walk %v", ret["truncated_walk"])
+	}
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Errorf("%d task requests, want both callers sharing one", n)
+	}
+}
+
+func TestSharedFetchFailureNotRetried(t *testing.T) {
+	for _, ttl := range []time.Duration{0, time.Minute} {
+		var requests int64
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/task" {
+				http.NotFound(w, r)
+				return
+			}
+			atomic.AddInt64(&requests, 1)
+			<-release
+			_, _ = w.Write([]byte(testTask))
+		}))
+		t.Setenv("ECS_CONTAINER_METADATA_URI_V4", srv.URL)
+		e := &Expvar{Client: testClient(), Timeout: time.Second, CacheTTL: ttl}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		if m := e.current(ctx); len(m) != 0 {
+			t.Errorf("CacheTTL %v: %v, want nothing once ctx ended", ttl, m)
+		}
+		cancel()
+		close(release)
+		e.mu.Lock()
+		f := e.inFlight
+		e.mu.Unlock()
+		if f != nil {
+			<-f.done
+		}
+		if n := atomic.LoadInt64(&requests); n != 1 {
+			t.Errorf("CacheTTL %v: %d task requests, want the failed crawl not started again", ttl, n)
+		}
+		srv.Close()
+	}
+}
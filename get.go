@@ -0,0 +1,49 @@
+package awsexpvar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Get reads the leaf of a snapshot at path as a T, so known leaves can be used without type assertions:
+//
+//	instanceID, err := awsexpvar.Get[string](snap, "meta-data", "instance-id")
+//	cpu, err := awsexpvar.Get[float64](snap, "task-metadata", "Limits", "CPU")
+//
+// Path segments are matched the same way Snapshot.Tags matches them.  The crawl stores most leaves as strings, so a
+// string leaf, like a number, is decoded as JSON into any other T.  Leaves of other types are converted through a
+// JSON round trip.
+func Get[T any](snapshot Snapshot, path ...string) (T, error) {
+	var ret T
+	val := lookup(map[string]interface{}(snapshot), path...)
+	if val == nil {
+		return ret, fmt.Errorf("%s: %w", strings.Join(path, "/"), ErrNotFound)
+	}
+	if n, ok := val.(json.Number); ok {
+		if typed, ok := val.(T); ok {
+			return typed, nil
+		}
+		val = string(n)
+	}
+	s, isString := val.(string)
+	if isString {
+		// IMDS leaves end in a newline
+		s = strings.TrimSpace(s)
+		val = s
+	}
+	if typed, ok := val.(T); ok {
+		return typed, nil
+	}
+	raw := []byte(s)
+	if !isString {
+		var err error
+		if raw, err = json.Marshal(val); err != nil {
+			return ret, fmt.Errorf("%s: %w", strings.Join(path, "/"), err)
+		}
+	}
+	if err := json.Unmarshal(raw, &ret); err != nil {
+		return ret, fmt.Errorf("%s: cannot read %T as %T: %w", strings.Join(path, "/"), val, ret, err)
+	}
+	return ret, nil
+}
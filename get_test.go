@@ -0,0 +1,37 @@
+package awsexpvar
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	snap := Snapshot{
+		"meta-data": map[string]interface{}{
+			"instance-id": "i-0123456789abcdef0\n",
+			"placement/":  map[string]string{"availability-zone": "us-west-2a"},
+		},
+		"task-metadata": map[string]interface{}{
+			"Limits":   map[string]interface{}{"CPU": 0.25, "Memory": float64(512)},
+			"Revision": "7",
+		},
+	}
+	if id, err := Get[string](snap, "meta-data", "instance-id"); err != nil || id != "i-0123456789abcdef0" {
+		t.Errorf("instance-id %q, %v", id, err)
+	}
+	if az, err := Get[string](snap, "meta-data", "placement", "availability-zone"); err != nil || az != "us-west-2a" {
+		t.Errorf("availability-zone %q, %v", az, err)
+	}
+	if cpu, err := Get[float64](snap, "task-metadata", "Limits", "CPU"); err != nil || cpu != 0.25 {
+		t.Errorf("CPU %v, %v", cpu, err)
+	}
+	if memory, err := Get[int](snap, "task-metadata", "Limits", "Memory"); err != nil || memory != 512 {
+		t.Errorf("Memory %v, %v", memory, err)
+	}
+	if revision, err := Get[int](snap, "task-metadata", "Revision"); err != nil || revision != 7 {
+		t.Errorf("string leaf Revision %v, %v", revision, err)
+	}
+	if _, err := Get[string](snap, "meta-data", "missing"); err == nil {
+		t.Error("missing leaf found")
+	}
+	if _, err := Get[int](snap, "meta-data", "instance-id"); err == nil {
+		t.Error("instance-id read as an int")
+	}
+}
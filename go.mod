@@ -1,3 +1,3 @@
 module github.com/cep21/awsexpvar
 
-go 1.12
+go 1.18
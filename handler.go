@@ -0,0 +1,127 @@
+package awsexpvar
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// adminRoute is the sub-route of Handler that reconfigures the Expvar at runtime
+const adminRoute = "/admin"
+
+// Handler serves one Expvar on its own, for processes that do not want to publish all of /debug/vars.  Requests
+// ending in /admin reconfigure the Expvar, and requests for a sub-path such as
+// /debug/aws/meta-data/placement/availability-zone return just that IMDS value; everything else renders the
+// Expvar.  The "format" query parameter picks "json" (the default), "indented", "text", "html", or a topology
+// diagram as "mermaid" or "dot".
+type Handler struct {
+	Expvar *Expvar
+	// Authorize is called before every request, and the request is refused with 403 if it returns an error.  The
+	// admin route is refused outright when Authorize is nil.
+	Authorize func(r *http.Request) error
+	// ProxySafelist, when not nil, switches sub-path proxying to a strict mode: only these paths, such as
+	// "meta-data/placement/availability-zone", are proxied and every other sub-path is refused with 403.  An empty,
+	// non-nil list turns proxying off.
+	ProxySafelist []string
+}
+
+// adminRequest is the body POSTed to the admin route.  Sections maps top level keys to whether they are enabled.
+// RefreshInterval changes how often Start refreshes.
+type adminRequest struct {
+	Sections        map[string]bool `json:"sections"`
+	RefreshInterval *Duration       `json:"refresh-interval,omitempty"`
+}
+
+type adminResponse struct {
+	DisabledSections []string `json:"disabled-sections"`
+	RefreshInterval  Duration `json:"refresh-interval,omitempty"`
+}
+
+var formats = map[string]Format{
+	"":         FormatJSON,
+	"json":     FormatJSON,
+	"indented": FormatIndentedJSON,
+	"text":     FormatText,
+	"html":     FormatHTML,
+	"mermaid":  FormatMermaid,
+	"dot":      FormatDOT,
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	admin := strings.HasSuffix(r.URL.Path, adminRoute)
+	if (admin && h.Authorize == nil) || !h.authorized(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if admin {
+		h.serveAdmin(w, r)
+		return
+	}
+	if path, ok := proxyPath(r.URL.Path); ok {
+		h.serveProxy(w, r, path)
+		return
+	}
+	format, ok := formats[r.URL.Query().Get("format")]
+	if !ok {
+		http.Error(w, "unknown format", http.StatusBadRequest)
+		return
+	}
+	switch format {
+	case FormatText, FormatMermaid:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	case FormatDOT:
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	case FormatHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	default:
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if err := h.Expvar.RenderTo(r.Context(), w, format); err != nil && h.Expvar.Log != nil {
+		h.Expvar.Log.Log("err", err, "unable to render metadata")
+	}
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	return h.Authorize == nil || h.Authorize(r) == nil
+}
+
+// serveAdmin reports the disabled sections and refresh interval on GET, and changes them on POST.  If Var is serving
+// a refreshed snapshot, a POST refreshes it so the change is visible immediately.
+func (h *Handler) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req adminRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.RefreshInterval != nil {
+			if err := h.Expvar.SetRefreshInterval(time.Duration(*req.RefreshInterval)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		for name, enabled := range req.Sections {
+			h.Expvar.SetSectionEnabled(name, enabled)
+		}
+		if h.Expvar.refreshed() {
+			if err := h.Expvar.Refresh(r.Context()); err != nil && h.Expvar.Log != nil {
+				h.Expvar.Log.Log("err", err, "unable to refresh after reconfiguration")
+			}
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	resp := adminResponse{
+		DisabledSections: h.Expvar.disabledSections(),
+		RefreshInterval:  Duration(h.Expvar.refreshInterval()),
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil && h.Expvar.Log != nil {
+		h.Expvar.Log.Log("err", err, "unable to write admin response")
+	}
+}
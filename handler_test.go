@@ -0,0 +1,100 @@
+package awsexpvar
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerAdminSections(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{fileEndpoint(t, testInstance)}}
+	h := &Handler{Expvar: e, Authorize: func(*http.Request) error { return nil }}
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+	if rec := serve(http.MethodGet, "/debug/aws", ""); !strings.Contains(rec.Body.String(), "i-0123456789abcdef0") {
+		t.Fatalf("meta-data missing: %s", rec.Body.String())
+	}
+	rec := serve(http.MethodPost, "/debug/aws/admin", `{"sections":{"meta-data":false}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp adminResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.DisabledSections, []string{"meta-data"}) {
+		t.Errorf("disabled-sections %v", resp.DisabledSections)
+	}
+	if rec := serve(http.MethodGet, "/debug/aws", ""); strings.Contains(rec.Body.String(), "i-0123456789abcdef0") {
+		t.Errorf("disabled meta-data rendered: %s", rec.Body.String())
+	}
+	if rec := serve(http.MethodGet, "/debug/aws?format=xml", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := serve(http.MethodDelete, "/debug/aws/admin", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE admin: status %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandlerAuthorize(t *testing.T) {
+	e := &Expvar{Client: testClient(), DisabledSections: []string{"meta-data"}}
+	rec := httptest.NewRecorder()
+	(&Handler{Expvar: e}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/aws/admin", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("admin without Authorize: status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	refuse := &Handler{Expvar: e, Authorize: func(*http.Request) error { return errors.New("no") }}
+	rec = httptest.NewRecorder()
+	refuse.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/aws", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("refused request: status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestHandlerAdminRefreshInterval(t *testing.T) {
+	e := &Expvar{RefreshInterval: time.Hour}
+	h := &Handler{Expvar: e, Authorize: func(*http.Request) error { return nil }}
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/aws/admin", strings.NewReader(body)))
+		return rec
+	}
+	for _, interval := range []string{"0s", "-1s"} {
+		if rec := post(`{"refresh-interval":"` + interval + `"}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("refresh-interval %s: status %d, want %d", interval, rec.Code, http.StatusBadRequest)
+		}
+	}
+	if got := e.refreshInterval(); got != time.Hour {
+		t.Fatalf("rejected interval changed the refresh interval to %s", got)
+	}
+	rec := post(`{"refresh-interval":"5m"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp adminResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(resp.RefreshInterval) != 5*time.Minute {
+		t.Errorf("refresh-interval %s, want 5m", time.Duration(resp.RefreshInterval))
+	}
+}
+
+func TestSetRefreshInterval(t *testing.T) {
+	var e Expvar
+	if err := e.SetRefreshInterval(0); err == nil {
+		t.Error("zero interval accepted")
+	}
+	if err := e.SetRefreshInterval(time.Minute); err != nil {
+		t.Error(err)
+	}
+}
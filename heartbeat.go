@@ -0,0 +1,104 @@
+package awsexpvar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// defaultHeartbeatInterval is used when Heartbeat.Interval is not set
+const defaultHeartbeatInterval = time.Minute
+
+// Publisher delivers a heartbeat payload somewhere
+type Publisher interface {
+	Publish(ctx context.Context, payload []byte) error
+}
+
+// HTTPPublisher POSTs each heartbeat as JSON to URL, typically an internal inventory endpoint
+type HTTPPublisher struct {
+	URL    string
+	Client *http.Client
+	// Header is added to every request.  Put authentication here, for example an Authorization header.
+	Header http.Header
+}
+
+// Publish sends payload, failing on any non 2xx response
+func (p *HTTPPublisher) Publish(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequest("POST", p.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range p.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat to %s: unexpected status %s", p.URL, resp.Status)
+	}
+	return nil
+}
+
+// Heartbeat periodically publishes the slim snapshot, turning every instrumented process into a fleet inventory
+// reporter
+type Heartbeat struct {
+	Expvar    *Expvar
+	Publisher Publisher
+	// Interval between heartbeats.  Defaults to one minute.
+	Interval time.Duration
+}
+
+type heartbeatPayload struct {
+	SentAt   time.Time         `json:"sentAt"`
+	Identity map[string]string `json:"identity"`
+}
+
+// Run publishes a heartbeat immediately and then every Interval until ctx is done.  Failed heartbeats are logged to
+// the Expvar's Logger and retried on the next tick.
+func (h *Heartbeat) Run(ctx context.Context) error {
+	interval := h.Interval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := h.Beat(ctx); err != nil && h.Expvar.Log != nil {
+			h.Expvar.Log.Log("err", err, "unable to publish heartbeat")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Beat publishes a single heartbeat
+func (h *Heartbeat) Beat(ctx context.Context) error {
+	payload, err := json.Marshal(heartbeatPayload{
+		SentAt:   time.Now(),
+		Identity: h.Expvar.Snapshot(ctx).Slim(),
+	})
+	if err != nil {
+		return err
+	}
+	return h.Publisher.Publish(ctx, payload)
+}
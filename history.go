@@ -0,0 +1,178 @@
+package awsexpvar
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historySize is how many changes "history" keeps, oldest dropped first
+const historySize = 128
+
+// volatileSections change on every fetch, so their changes would crowd real ones out of "history"
+var volatileSections = []string{"_stats", "uptime", "clock-skew", "task-stats", "filesystem", "identity-changes",
+	"history"}
+
+// Values of historyEvent for sections that appeared or disappeared as a whole
+const (
+	historyAppeared    = "(appeared)"
+	historyDisappeared = "(disappeared)"
+)
+
+// historyEvent is one leaf that changed between two fetches, such as a spot interruption notice appearing under
+// "meta-data/spot/instance-action"
+type historyEvent struct {
+	Path string    `json:"path"`
+	Old  string    `json:"old"`
+	New  string    `json:"new"`
+	At   time.Time `json:"at"`
+}
+
+// recordHistory compares the leaves of ret against the previous fetch, and publishes the latest changes under
+// "history".  A section that appears or disappears as a whole, such as meta-data when IMDS stops answering, is one
+// change rather than one per leaf.
+func (e *Expvar) recordHistory(ret map[string]interface{}) {
+	leaves := make(map[string]map[string]string, len(ret))
+	for name, val := range ret {
+		if containsString(volatileSections, name) {
+			continue
+		}
+		leaves[name] = flattenLeaves(val)
+	}
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastLeaves != nil {
+		e.history = append(e.history, diffSections(e.lastLeaves, leaves, now)...)
+		if len(e.history) > historySize {
+			e.history = append([]historyEvent(nil), e.history[len(e.history)-historySize:]...)
+		}
+	}
+	e.lastLeaves = leaves
+	if len(e.history) > 0 {
+		ret["history"] = append([]historyEvent(nil), e.history...)
+	}
+}
+
+// dropHistory removes from the "history" of a decoded snapshot every change at or under one of paths, so a path
+// removed from the output cannot be read back from its old and new values
+func dropHistory(ret map[string]interface{}, paths [][]string) {
+	events, ok := ret["history"].([]interface{})
+	if !ok {
+		return
+	}
+	kept := make([]interface{}, 0, len(events))
+	for _, event := range events {
+		path := strings.Split(lookupString(event, "path"), "/")
+		removed := false
+		for _, p := range paths {
+			if hasPrefixPath(path, p) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, event)
+		}
+	}
+	if len(kept) == 0 {
+		delete(ret, "history")
+		return
+	}
+	ret["history"] = kept
+}
+
+func diffSections(old map[string]map[string]string, current map[string]map[string]string, now time.Time) []historyEvent {
+	var ret []historyEvent
+	for _, name := range sortedSectionNames(old, current) {
+		before, hadBefore := old[name]
+		after, hasAfter := current[name]
+		switch {
+		case !hadBefore:
+			ret = append(ret, historyEvent{Path: name, New: historyAppeared, At: now})
+		case !hasAfter:
+			ret = append(ret, historyEvent{Path: name, Old: historyDisappeared, At: now})
+		default:
+			ret = append(ret, diffLeaves(name, before, after, now)...)
+		}
+	}
+	return ret
+}
+
+func diffLeaves(section string, before map[string]string, after map[string]string, now time.Time) []historyEvent {
+	paths := make([]string, 0, len(after))
+	for path := range before {
+		paths = append(paths, path)
+	}
+	for path := range after {
+		if _, exists := before[path]; !exists {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	var ret []historyEvent
+	for _, path := range paths {
+		if before[path] != after[path] {
+			full := section
+			if path != "" {
+				full += "/" + path
+			}
+			ret = append(ret, historyEvent{Path: full, Old: before[path], New: after[path], At: now})
+		}
+	}
+	return ret
+}
+
+func sortedSectionNames(a map[string]map[string]string, b map[string]map[string]string) []string {
+	names := make([]string, 0, len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, exists := a[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flattenLeaves maps the path of every leaf of v, written the way Transform takes paths, to its value.  A leaf
+// section maps "" to its value.
+func flattenLeaves(v interface{}) map[string]string {
+	// Round trip through JSON so structs flatten the same way they are published
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var generic interface{}
+	if err := decodeJSON(b, &generic); err != nil {
+		return nil
+	}
+	ret := make(map[string]string)
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		join := func(key string) string {
+			if prefix == "" {
+				return key
+			}
+			return prefix + "/" + key
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for k, child := range val {
+				walk(join(strings.TrimSuffix(k, "/")), child)
+			}
+		case []interface{}:
+			for i, child := range val {
+				walk(join(strconv.Itoa(i)), child)
+			}
+		default:
+			ret[prefix] = leafText(val)
+		}
+	}
+	walk("", generic)
+	return ret
+}
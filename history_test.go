@@ -0,0 +1,51 @@
+package awsexpvar
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRecordHistory(t *testing.T) {
+	var e Expvar
+	first := map[string]interface{}{
+		"meta-data": map[string]interface{}{"placement/": map[string]string{"availability-zone": "us-west-2a"}},
+		"uptime":    map[string]interface{}{"process-seconds": 1},
+	}
+	e.recordHistory(first)
+	if _, exists := first["history"]; exists {
+		t.Fatalf("history on the first fetch: %v", first["history"])
+	}
+	second := map[string]interface{}{
+		"meta-data": map[string]interface{}{"placement/": map[string]string{"availability-zone": "us-west-2b"}},
+		"uptime":    map[string]interface{}{"process-seconds": 2},
+		"user-data": "#!/bin/bash",
+	}
+	e.recordHistory(second)
+	events, _ := second["history"].([]historyEvent)
+	if len(events) != 2 {
+		t.Fatalf("events %+v, want the availability zone and user-data", events)
+	}
+	if got := events[0]; got.Path != "meta-data/placement/availability-zone" || got.Old != "us-west-2a" ||
+		got.New != "us-west-2b" {
+		t.Errorf("change %+v", got)
+	}
+	if got := events[1]; got.Path != "user-data" || got.New != historyAppeared {
+		t.Errorf("appearance %+v", got)
+	}
+}
+
+func TestRecordHistoryBounded(t *testing.T) {
+	var e Expvar
+	var ret map[string]interface{}
+	for i := 0; i < historySize+10; i++ {
+		ret = map[string]interface{}{"user-data": strconv.Itoa(i)}
+		e.recordHistory(ret)
+	}
+	events, _ := ret["history"].([]historyEvent)
+	if len(events) != historySize {
+		t.Fatalf("%d events, want %d", len(events), historySize)
+	}
+	if last := events[len(events)-1]; last.New != strconv.Itoa(historySize+9) {
+		t.Errorf("latest change %+v", last)
+	}
+}
@@ -0,0 +1,143 @@
+package awsexpvar
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const htmlHead = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>AWS metadata</title>
+<style>
+body { font-family: monospace; }
+details { margin-left: 1.5em; }
+.leaf { margin-left: 1.5em; }
+.key { font-weight: bold; }
+.error { background: #fdd; }
+.stale { background: #ffd; }
+button { font-size: smaller; margin-left: 0.5em; }
+</style></head><body>
+`
+
+const htmlTail = `<script>
+document.querySelectorAll("button[data-copy]").forEach(function (b) {
+	b.addEventListener("click", function () { navigator.clipboard.writeText(b.dataset.copy); });
+});
+</script></body></html>
+`
+
+// renderHTML is a collapsible tree for people on call.  Sections that failed and snapshots that missed their refresh
+// are highlighted, and IDs and ARNs get a copy button.
+func renderHTML(w io.Writer, m map[string]interface{}) error {
+	// Round trip through JSON so structs flatten the same way they are published
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var generic map[string]interface{}
+	if err := decodeJSON(b, &generic); err != nil {
+		return err
+	}
+	// sections that failed are missing from the output, so their entries under _sections are what is highlighted
+	failed := make(map[string]string)
+	if sections, ok := generic["_sections"].(map[string]interface{}); ok {
+		for name := range sections {
+			if lookupString(sections, name, "status") == SectionUnavailable {
+				failed[name] = "error"
+			}
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString(htmlHead)
+	if stale(generic) {
+		buf.WriteString(`<p class="stale">This snapshot missed its refresh (see next_refresh_at).</p>` + "\n")
+	}
+	if _, truncated := generic["truncated_walk"]; truncated {
+		buf.WriteString(`<p class="error">The walk was truncated, so some values are missing.</p>` + "\n")
+	}
+	for _, k := range sortedKeys(generic) {
+		var classes map[string]string
+		if k == "_sections" {
+			classes = failed
+		}
+		htmlNode(&buf, k, generic[k], "", true, classes)
+	}
+	buf.WriteString(htmlTail)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// stale is true when a snapshot kept refreshed by Start is past its next refresh
+func stale(m map[string]interface{}) bool {
+	next, ok := m["next_refresh_at"].(string)
+	if !ok {
+		return false
+	}
+	at, err := time.Parse(time.RFC3339Nano, next)
+	return err == nil && time.Now().After(at)
+}
+
+// htmlNode writes v and its children, each child with its class in childClasses
+func htmlNode(buf *bytes.Buffer, key string, v interface{}, class string, open bool, childClasses map[string]string) {
+	classAttr := ""
+	if class != "" {
+		classAttr = ` class="` + class + `"`
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		buf.WriteString("<details" + classAttr)
+		if open {
+			buf.WriteString(" open")
+		}
+		buf.WriteString(`><summary class="key">` + html.EscapeString(key) + "</summary>\n")
+		for _, k := range sortedKeys(val) {
+			htmlNode(buf, k, val[k], childClasses[k], childClasses[k] != "", nil)
+		}
+		buf.WriteString("</details>\n")
+	case []interface{}:
+		buf.WriteString("<details" + classAttr + `><summary class="key">` + html.EscapeString(key) + "</summary>\n")
+		for i, child := range val {
+			htmlNode(buf, "["+strconv.Itoa(i)+"]", child, "", false, nil)
+		}
+		buf.WriteString("</details>\n")
+	default:
+		text := leafText(val)
+		buf.WriteString(`<div class="leaf`)
+		if class != "" {
+			buf.WriteString(" " + class)
+		}
+		buf.WriteString(`"><span class="key">` + html.EscapeString(key) + "</span>: " + html.EscapeString(text))
+		if copyable(key, text) {
+			buf.WriteString(`<button data-copy="` + html.EscapeString(text) + `">copy</button>`)
+		}
+		buf.WriteString("</div>\n")
+	}
+}
+
+func leafText(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// copyable picks out the values people paste into consoles and tickets: IDs and ARNs
+func copyable(key string, val string) bool {
+	lower := strings.ToLower(key)
+	return strings.HasPrefix(val, "arn:") || strings.HasSuffix(lower, "id") || strings.HasSuffix(lower, "arn")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
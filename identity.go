@@ -0,0 +1,31 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// identityDocument is the subset of the instance identity document we interpret
+type identityDocument struct {
+	AccountID        string `json:"accountId"`
+	Architecture     string `json:"architecture"`
+	AvailabilityZone string `json:"availabilityZone"`
+	ImageID          string `json:"imageId"`
+	InstanceID       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+	PendingTime      string `json:"pendingTime"`
+	PrivateIP        string `json:"privateIp"`
+	Region           string `json:"region"`
+}
+
+func (e *Expvar) identityDocument(ctx context.Context) (*identityDocument, error) {
+	b, err := e.imdsGet(ctx, instanceIdentPath)
+	if err != nil {
+		return nil, err
+	}
+	var doc identityDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
@@ -0,0 +1,67 @@
+package awsexpvar
+
+import (
+	"context"
+	"testing"
+)
+
+// testIdentity is an identity document with the array fields of marketplace and licensed AMIs
+const testIdentity = `{
+	"accountId": "123456789012",
+	"architecture": "x86_64",
+	"availabilityZone": "us-west-2a",
+	"billingProducts": ["bp-6ba54002"],
+	"devpayProductCodes": null,
+	"marketplaceProductCodes": ["1abc2defghijklm3nopqrs4tu"],
+	"imageId": "ami-0123456789abcdef0",
+	"instanceId": "i-0123456789abcdef0",
+	"instanceType": "m5.large",
+	"pendingTime": "2026-10-14T12:00:00Z",
+	"privateIp": "10.0.0.5",
+	"region": "us-west-2"
+}`
+
+// regionTagger is an InstanceTagger that tags every instance with the region it was asked about
+type regionTagger struct{}
+
+func (regionTagger) InstanceTags(_ context.Context, region string, _ string) (map[string]string, error) {
+	return map[string]string{"asked-region": region}, nil
+}
+
+func TestInstanceIdentityArrays(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	t.Setenv("AWS_REGION", "us-east-1")
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{fileEndpoint(t, map[string]string{
+		"latest/dynamic/instance-identity/document": testIdentity,
+		"latest/meta-data/instance-id":              "i-0123456789abcdef0",
+	})}, TagFallback: regionTagger{}}
+	ret := published(t, e)
+	identity, _ := ret["instance-identity"].(map[string]interface{})
+	if identity["region"] != "us-west-2" {
+		t.Fatalf("instance-identity %v", ret["instance-identity"])
+	}
+	if products, _ := identity["billingProducts"].([]interface{}); len(products) != 1 {
+		t.Errorf("billingProducts %v", identity["billingProducts"])
+	}
+	if warning, _ := ret["region-warning"].(map[string]interface{}); warning["metadata-region"] != "us-west-2" {
+		t.Errorf("region-warning %v", ret["region-warning"])
+	}
+	if sts, _ := ret["sts"].(map[string]interface{}); sts["region"] != "us-west-2" {
+		t.Errorf("sts %v", ret["sts"])
+	}
+	if endpoints, _ := ret["endpoints"].(map[string]interface{}); endpoints["ecr-registry"] !=
+		"123456789012.dkr.ecr.us-west-2.amazonaws.com" {
+		t.Errorf("endpoints %v", ret["endpoints"])
+	}
+	if uptime, _ := ret["uptime"].(map[string]interface{}); uptime["instance-pending-time"] == nil {
+		t.Errorf("uptime %v", ret["uptime"])
+	}
+	instanceTags, _ := ret["instance-tags"].(map[string]interface{})
+	if asked := lookupString(instanceTags, "tags", "asked-region"); asked != "us-west-2" {
+		t.Errorf("instance-tags %v", ret["instance-tags"])
+	}
+	tags := Snapshot(ret).Tags()
+	if tags["aws:region"] != "us-west-2" || tags["aws:accountId"] != "123456789012" {
+		t.Errorf("tags %v", tags)
+	}
+}
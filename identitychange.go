@@ -0,0 +1,57 @@
+package awsexpvar
+
+import "time"
+
+// IdentityChange is reported when the instance or task this process runs as changes between two fetches, for example
+// after a checkpoint/restore or a microVM snapshot.  Silent identity changes break metric continuity.
+type IdentityChange struct {
+	Field string    `json:"field"`
+	Old   string    `json:"old"`
+	New   string    `json:"new"`
+	At    time.Time `json:"at"`
+}
+
+// identityPaths are the values that must never change for the lifetime of a process
+var identityPaths = map[string][]string{
+	"instance-id": {"meta-data", "instance-id"},
+	"task-arn":    {"task-metadata", "TaskARN"},
+}
+
+// detectIdentityChange compares ret against the previous fetch, and records under "identity-changes" every change
+// seen by this process
+func (e *Expvar) detectIdentityChange(ret map[string]interface{}) {
+	now := time.Now()
+	var changes []IdentityChange
+	e.mu.Lock()
+	if e.lastIdentity == nil {
+		e.lastIdentity = make(map[string]string, len(identityPaths))
+	}
+	for field, path := range identityPaths {
+		current := lookupString(ret, path...)
+		if current == "" {
+			continue
+		}
+		if previous := e.lastIdentity[field]; previous != "" && previous != current {
+			changes = append(changes, IdentityChange{
+				Field: field,
+				Old:   previous,
+				New:   current,
+				At:    now,
+			})
+		}
+		e.lastIdentity[field] = current
+	}
+	e.identityChanges = append(e.identityChanges, changes...)
+	if len(e.identityChanges) > 0 {
+		ret["identity-changes"] = append([]IdentityChange(nil), e.identityChanges...)
+	}
+	e.mu.Unlock()
+	for _, change := range changes {
+		if e.Log != nil {
+			e.Log.Log("field", change.Field, "old", change.Old, "new", change.New, "identity changed")
+		}
+		if e.OnIdentityChange != nil {
+			e.OnIdentityChange(change)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package awsexpvar
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IMDS endpoints usable in MetadataEndpoints
+const (
+	IMDSEndpoint     = "http://169.254.169.254"
+	IMDSIPv6Endpoint = "http://[fd00:ec2::254]"
+)
+
+// IMDSClient fetches a path of the instance metadata service, such as "/latest/meta-data/instance-id", and returns
+// ErrNotFound for paths that do not exist.  See the awssdk module for an adapter of aws-sdk-go-v2's imds.Client.
+type IMDSClient interface {
+	Get(ctx context.Context, path string) ([]byte, error)
+}
+
+// DefaultMetadataEndpoints is used when MetadataEndpoints is empty
+var DefaultMetadataEndpoints = []string{IMDSEndpoint}
+
+func (e *Expvar) metadataEndpoints() []string {
+	if len(e.MetadataEndpoints) == 0 {
+		return DefaultMetadataEndpoints
+	}
+	return e.MetadataEndpoints
+}
+
+// get fetches target, which is either a path relative to the metadata endpoints or an absolute URL
+func (e *Expvar) get(ctx context.Context, target string) ([]byte, error) {
+	release, err := acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if strings.HasPrefix(target, "/") {
+		return e.imdsGet(ctx, target)
+	}
+	return e.getBody(ctx, target)
+}
+
+// GetRaw returns the body of an IMDS path, such as "/latest/dynamic/fws/instance-monitoring", exactly as it was
+// served.  It goes through the same endpoints, tokens and IMDS as the crawl, but nothing is parsed, so it suits
+// binary and vendor specific documents.  Missing paths return ErrNotFound.
+func (e *Expvar) GetRaw(ctx context.Context, path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return e.imdsGet(ctx, path)
+}
+
+// imdsGet tries each metadata endpoint in order, starting from the last one that answered.  A not found is an
+// answer, so it does not fail over.
+func (e *Expvar) imdsGet(ctx context.Context, path string) ([]byte, error) {
+	if w := walkFrom(ctx); w != nil && w.skipIMDS {
+		return nil, errNotApplicable
+	}
+	ctx = withSource(ctx, sourceIMDS)
+	if e.IMDS != nil {
+		if !takeRequest(ctx) {
+			return nil, errRequestBudget
+		}
+		start := time.Now()
+		b, err := e.IMDS.Get(ctx, path)
+		e.stats.recordLatency(sourceIMDS, time.Since(start))
+		if err == nil || errors.Is(err, ErrNotFound) {
+			e.setIMDSMode(IMDSModeSDK)
+		}
+		return b, err
+	}
+	endpoints := e.metadataEndpoints()
+	e.mu.Lock()
+	start := e.activeEndpoint
+	e.mu.Unlock()
+	var lastErr error
+	for i := 0; i < len(endpoints); i++ {
+		idx := (start + i) % len(endpoints)
+		b, err := e.endpointGet(ctx, endpoints[idx], path)
+		if err == nil || err == ErrNotFound {
+			if idx != start {
+				e.mu.Lock()
+				e.activeEndpoint = idx
+				e.mu.Unlock()
+			}
+			return b, err
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (e *Expvar) endpointGet(ctx context.Context, endpoint string, path string) ([]byte, error) {
+	if strings.HasPrefix(endpoint, "file://") {
+		return fileGet(endpoint, path)
+	}
+	base := strings.TrimSuffix(endpoint, "/")
+	return e.tokenGet(ctx, base, path)
+}
+
+// errOutsideFileEndpoint is returned for paths, such as those with ".." segments, that resolve outside the directory
+// of a file:// endpoint
+var errOutsideFileEndpoint = errors.New("path resolves outside the file endpoint")
+
+// fileGet serves path out of a directory laid out like IMDS.  Directories are listed the way IMDS lists them: one
+// entry per line, with a trailing slash on subdirectories.  Paths that clean to somewhere outside the directory are
+// refused, whether they come from the crawl, GetRaw or the Handler proxy.
+func fileGet(endpoint string, path string) ([]byte, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Clean(filepath.FromSlash(u.Path))
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if rel, err := filepath.Rel(root, full); err != nil || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, errOutsideFileEndpoint
+	}
+	info, err := os.Stat(full)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return ioutil.ReadFile(full)
+	}
+	entries, err := ioutil.ReadDir(full)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	return []byte(strings.Join(names, "\n")), nil
+}
@@ -0,0 +1,113 @@
+package awsexpvar
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fileEndpoint lays out files, keyed by slash separated path, under a temporary directory and returns it as a
+// file:// metadata endpoint
+func fileEndpoint(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for path, body := range files {
+		full := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(full, []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return "file://" + filepath.ToSlash(dir)
+}
+
+// testInstance is the IMDS tree of a small instance
+var testInstance = map[string]string{
+	"latest/meta-data/instance-id":                 "i-0123456789abcdef0",
+	"latest/meta-data/instance-type":               "m5.large",
+	"latest/meta-data/placement/availability-zone": "us-west-2a",
+	"latest/meta-data/placement/region":            "us-west-2",
+}
+
+func TestFileGet(t *testing.T) {
+	endpoint := fileEndpoint(t, testInstance)
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{path: "/latest/meta-data/instance-id", want: "i-0123456789abcdef0"},
+		{path: "/latest/meta-data/", want: "instance-id\ninstance-type\nplacement/"},
+		{path: "/latest/meta-data/placement", want: "availability-zone\nregion"},
+	} {
+		b, err := fileGet(endpoint, tc.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.path, err)
+		}
+		if string(b) != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.path, b, tc.want)
+		}
+	}
+	if _, err := fileGet(endpoint, "/latest/meta-data/missing"); err == nil {
+		t.Error("missing path found")
+	}
+}
+
+func TestFileGetOutsideEndpoint(t *testing.T) {
+	endpoint := fileEndpoint(t, map[string]string{
+		"imds/latest/meta-data/instance-id": "i-0123456789abcdef0",
+		"outside.txt":                       "not metadata",
+	}) + "/imds"
+	for _, path := range []string{"/../outside.txt", "/latest/../../outside.txt", "latest/meta-data/../../../outside.txt"} {
+		if b, err := fileGet(endpoint, path); err != errOutsideFileEndpoint {
+			t.Errorf("%s: got %q, %v, want errOutsideFileEndpoint", path, b, err)
+		}
+	}
+	e := &Expvar{MetadataEndpoints: []string{endpoint}}
+	if b, err := e.GetRaw(context.Background(), "../outside.txt"); err == nil {
+		t.Errorf("GetRaw outside the endpoint: %q", b)
+	}
+	// the crawl joins directories and leaves with a doubled slash
+	b, err := fileGet(endpoint, "/latest/meta-data//instance-id")
+	if err != nil || string(b) != "i-0123456789abcdef0" {
+		t.Errorf("doubled slash: %q, %v", b, err)
+	}
+}
+
+func TestFileEndpoint(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{fileEndpoint(t, testInstance)}}
+	metaData, _ := published(t, e)["meta-data"].(map[string]interface{})
+	if id := metaData["instance-id"]; id != "i-0123456789abcdef0" {
+		t.Errorf("instance-id %v", id)
+	}
+	placement, _ := metaData["placement/"].(map[string]interface{})
+	if az := placement["availability-zone"]; az != "us-west-2a" {
+		t.Errorf("availability-zone %v", az)
+	}
+}
+
+func TestMetadataEndpointFailover(t *testing.T) {
+	down := httptest.NewServer(nil)
+	down.Close()
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{down.URL, fileEndpoint(t, testInstance)}}
+	for i := 0; i < 2; i++ {
+		b, err := e.imdsGet(context.Background(), "/latest/meta-data/instance-id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != "i-0123456789abcdef0" {
+			t.Errorf("got %q", b)
+		}
+	}
+	e.mu.Lock()
+	active := e.activeEndpoint
+	e.mu.Unlock()
+	if active != 1 {
+		t.Errorf("active endpoint %d, want the file endpoint that answered", active)
+	}
+}
@@ -0,0 +1,189 @@
+package awsexpvar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IMDSv2 session tokens are PUT for, then sent along with every metadata request
+const (
+	tokenPath       = "/latest/api/token"
+	tokenHeader     = "X-aws-ec2-metadata-token"
+	tokenTTLHeader  = "X-aws-ec2-metadata-token-ttl-seconds"
+	tokenTTLSeconds = "21600"
+	tokenTTL        = 21600 * time.Second
+	// tokenRefreshMargin renews tokens this long before they expire, so a token never expires mid walk
+	tokenRefreshMargin = time.Minute
+	// tokenRetryAfter is how long an endpoint that refused to hand out a token is spoken to with IMDSv1
+	tokenRetryAfter = time.Minute
+)
+
+// IMDS modes reported under "imds_mode".  v1-fallback means the token request failed and IMDSv1 was used instead,
+// which in containers usually means the instance's HttpPutResponseHopLimit is too low.  sdk means requests went
+// through Expvar.IMDS, such as aws-sdk-go-v2's client, which manages its own IMDSv2 tokens.
+const (
+	IMDSModeV2            = "v2"
+	IMDSModeV1Fallback    = "v1-fallback"
+	IMDSModeV2Unavailable = "v2-unavailable"
+	IMDSModeSDK           = "sdk"
+)
+
+// errNoToken is returned for IMDS requests that could not get a token while IMDSv1 fallback is disabled
+var errNoToken = errors.New("no IMDSv2 token could be obtained and IMDSv1 fallback is disabled")
+
+// imdsToken is a session token of one endpoint.  An empty value caches that the endpoint refused to hand one out.
+type imdsToken struct {
+	value   string
+	expires time.Time
+}
+
+// tokenFlight is one token request of an endpoint, which concurrent requests to that endpoint wait on instead of each
+// asking for a token of their own
+type tokenFlight struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// requestToken requests an IMDSv2 session token from endpoint
+func (e *Expvar) requestToken(ctx context.Context, endpoint string) (string, error) {
+	header := make(http.Header)
+	header.Set(tokenTTLHeader, tokenTTLSeconds)
+	b, status, err := e.do(ctx, e.client(), http.MethodPut, endpoint+tokenPath, header)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("token request answered %d", status)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// token is the session token of endpoint, reused until it nears expiry, with concurrent callers sharing one token
+// request.  It is empty when the endpoint does not hand out tokens.  An error means the token request never got a
+// fair chance, because ctx ended or the walk ran out of requests, and says nothing about the endpoint.
+func (e *Expvar) token(ctx context.Context, endpoint string) (string, error) {
+	now := time.Now()
+	e.mu.Lock()
+	if cached, ok := e.imdsTokens[endpoint]; ok && now.Before(cached.expires) {
+		e.mu.Unlock()
+		return cached.value, nil
+	}
+	if f := e.tokenRequests[endpoint]; f != nil {
+		e.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.value, f.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	f := &tokenFlight{done: make(chan struct{})}
+	if e.tokenRequests == nil {
+		e.tokenRequests = make(map[string]*tokenFlight)
+	}
+	e.tokenRequests[endpoint] = f
+	e.mu.Unlock()
+	f.value, f.err = e.newToken(ctx, endpoint, now)
+	e.mu.Lock()
+	delete(e.tokenRequests, endpoint)
+	e.mu.Unlock()
+	close(f.done)
+	return f.value, f.err
+}
+
+// newToken requests a token of endpoint and caches the answer, refusals included, unless the request was cut short
+func (e *Expvar) newToken(ctx context.Context, endpoint string, now time.Time) (string, error) {
+	value, err := e.requestToken(ctx, endpoint)
+	if err != nil && (errors.Is(err, errRequestBudget) || ctx.Err() != nil) {
+		return "", err
+	}
+	if err == nil && value == "" {
+		err = errors.New("empty token")
+	}
+	e.stats.recordToken(now.Add(tokenTTL), err)
+	cached := imdsToken{
+		value:   value,
+		expires: now.Add(tokenTTL - tokenRefreshMargin),
+	}
+	if err != nil {
+		cached = imdsToken{expires: now.Add(tokenRetryAfter)}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.imdsTokens == nil {
+		e.imdsTokens = make(map[string]imdsToken)
+	}
+	e.imdsTokens[endpoint] = cached
+	return cached.value, nil
+}
+
+// dropToken forgets the token of endpoint, for when the endpoint stops accepting it
+func (e *Expvar) dropToken(endpoint string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.imdsTokens, endpoint)
+}
+
+// tokenGet authenticates a request to endpoint with IMDSv2 when the endpoint hands out tokens.  Otherwise the request
+// goes out as IMDSv1, which mocks and older metadata proxies still expect, unless DisableIMDSv1Fallback is set.  A
+// token that is refused, because IMDS restarted or the token was revoked, is replaced once.
+func (e *Expvar) tokenGet(ctx context.Context, endpoint string, path string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := e.token(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if token == "" && e.DisableIMDSv1Fallback {
+			e.setIMDSMode(IMDSModeV2Unavailable)
+			return nil, errNoToken
+		}
+		var header http.Header
+		mode := IMDSModeV1Fallback
+		if token != "" {
+			header = make(http.Header)
+			header.Set(tokenHeader, token)
+			mode = IMDSModeV2
+		}
+		e.setIMDSMode(mode)
+		b, status, err := e.do(ctx, e.client(), http.MethodGet, endpoint+path, header)
+		if status == http.StatusUnauthorized && token != "" && attempt == 0 {
+			e.dropToken(endpoint)
+			continue
+		}
+		return b, err
+	}
+}
+
+// setIMDSMode logs falling back to IMDSv1, once per fallback, so it does not go unnoticed
+func (e *Expvar) setIMDSMode(mode string) {
+	e.mu.Lock()
+	changed := e.imdsMode != mode
+	e.imdsMode = mode
+	e.mu.Unlock()
+	if changed && mode == IMDSModeV1Fallback && e.Log != nil {
+		e.Log.Log("imds_mode", mode, "unable to get an IMDSv2 token, falling back to IMDSv1")
+	}
+}
+
+// imdsModeOf is how the latest IMDS request was made, or nil before any IMDS request
+func (e *Expvar) imdsModeOf() interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.imdsMode == "" {
+		return nil
+	}
+	return e.imdsMode
+}
+
+// usedIMDSv2 is true when the latest IMDS request was authenticated with a token, which the SDK's client always
+// tries to be
+func (e *Expvar) usedIMDSv2() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.imdsMode == IMDSModeV2 || e.imdsMode == IMDSModeSDK
+}
@@ -0,0 +1,169 @@
+package awsexpvar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenIMDS is an IMDS that requires session tokens, counting the token requests it answers
+type tokenIMDS struct {
+	puts int64
+	// putDelay holds token requests, so concurrent callers overlap
+	putDelay time.Duration
+}
+
+func (m *tokenIMDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut && r.URL.Path == tokenPath {
+		atomic.AddInt64(&m.puts, 1)
+		time.Sleep(m.putDelay)
+		_, _ = w.Write([]byte("token-value"))
+		return
+	}
+	if r.Header.Get(tokenHeader) != "token-value" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.URL.Path == metadataPath:
+		_, _ = w.Write([]byte("instance-id\nplacement/"))
+	case strings.HasSuffix(r.URL.Path, "placement/"):
+		_, _ = w.Write([]byte("availability-zone\nregion"))
+	default:
+		_, _ = w.Write([]byte("value"))
+	}
+}
+
+func TestIMDSv2(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	srv := httptest.NewServer(&tokenIMDS{})
+	defer srv.Close()
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{srv.URL}}
+	metaData, _ := published(t, e)["meta-data"].(map[string]interface{})
+	if id := metaData["instance-id"]; id != "value" {
+		t.Errorf("instance-id %v", id)
+	}
+	if !e.usedIMDSv2() {
+		t.Error("token was not used")
+	}
+}
+
+func TestIMDSv1Fallback(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodGet:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == metadataPath:
+			_, _ = w.Write([]byte("instance-id"))
+		default:
+			_, _ = w.Write([]byte("value"))
+		}
+	}))
+	defer srv.Close()
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{srv.URL}}
+	metaData, _ := published(t, e)["meta-data"].(map[string]interface{})
+	if id := metaData["instance-id"]; id != "value" {
+		t.Errorf("instance-id %v", id)
+	}
+	if e.usedIMDSv2() {
+		t.Error("IMDSv2 reported for an endpoint without tokens")
+	}
+}
+
+func TestTokenCached(t *testing.T) {
+	imds := &tokenIMDS{}
+	srv := httptest.NewServer(imds)
+	defer srv.Close()
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{srv.URL}}
+	for i := 0; i < 3; i++ {
+		if _, err := e.tokenGet(context.Background(), srv.URL, metadataPath+"instance-id"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if puts := atomic.LoadInt64(&imds.puts); puts != 1 {
+		t.Errorf("%d token requests, want 1", puts)
+	}
+}
+
+func TestTokenReplacedWhenRefused(t *testing.T) {
+	imds := &tokenIMDS{}
+	srv := httptest.NewServer(imds)
+	defer srv.Close()
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{srv.URL}}
+	e.imdsTokens = map[string]imdsToken{srv.URL: {value: "revoked", expires: time.Now().Add(time.Hour)}}
+	b, err := e.tokenGet(context.Background(), srv.URL, metadataPath+"instance-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "value" {
+		t.Errorf("got %q, want value", b)
+	}
+	if puts := atomic.LoadInt64(&imds.puts); puts != 1 {
+		t.Errorf("%d token requests, want 1", puts)
+	}
+}
+
+func TestTokenShared(t *testing.T) {
+	imds := &tokenIMDS{putDelay: 20 * time.Millisecond}
+	srv := httptest.NewServer(imds)
+	defer srv.Close()
+	e := &Expvar{MetadataEndpoints: []string{srv.URL}, DisableIMDSv1Fallback: true}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := e.tokenGet(context.Background(), srv.URL, metadataPath+"instance-id"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if puts := atomic.LoadInt64(&imds.puts); puts != 1 {
+		t.Errorf("%d token requests, want 1", puts)
+	}
+}
+
+func TestTokenNotCachedAfterCancel(t *testing.T) {
+	imds := &tokenIMDS{putDelay: 50 * time.Millisecond}
+	srv := httptest.NewServer(imds)
+	defer srv.Close()
+	e := &Expvar{MetadataEndpoints: []string{srv.URL}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := e.tokenGet(ctx, srv.URL, metadataPath+"instance-id"); err == nil {
+		t.Fatal("request with an expired context succeeded")
+	}
+	b, err := e.tokenGet(context.Background(), srv.URL, metadataPath+"instance-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "value" {
+		t.Errorf("got %q, want value", b)
+	}
+	if mode := e.imdsModeOf(); mode != IMDSModeV2 {
+		t.Errorf("imds_mode %v, want %s", mode, IMDSModeV2)
+	}
+}
+
+func TestTokenNotCachedAfterBudget(t *testing.T) {
+	srv := httptest.NewServer(&tokenIMDS{})
+	defer srv.Close()
+	e := &Expvar{MetadataEndpoints: []string{srv.URL}}
+	spent := &walk{maxRequests: 1, requests: 1}
+	if _, err := e.tokenGet(withWalk(context.Background(), spent), srv.URL, metadataPath+"instance-id"); err == nil {
+		t.Fatal("request past the budget succeeded")
+	}
+	if _, err := e.tokenGet(context.Background(), srv.URL, metadataPath+"instance-id"); err != nil {
+		t.Fatal(err)
+	}
+	if mode := e.imdsModeOf(); mode != IMDSModeV2 {
+		t.Errorf("imds_mode %v, want %s", mode, IMDSModeV2)
+	}
+}
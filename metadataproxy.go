@@ -0,0 +1,79 @@
+package awsexpvar
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// imdsServer is the Server header of every real IMDS response
+const imdsServer = "EC2ws"
+
+// knownProxies maps a substring of the Server header to the IMDS proxy or emulator that sends it
+var knownProxies = []struct {
+	server string
+	name   string
+}{
+	{server: "kiam", name: "kiam"},
+	{server: "kube2iam", name: "kube2iam"},
+	{server: "ec2-metadata-mock", name: "ec2-metadata-mock"},
+	{server: "aemm", name: "ec2-metadata-mock"},
+}
+
+// metadataProxy is reported under "metadata_proxy" when IMDS answers unlike the real one, which explains output that
+// is partial or subtly different: proxies such as kiam and kube2iam only pass some paths through, and emulators
+// serve canned values
+type metadataProxy struct {
+	Name     string `json:"name"`
+	Server   string `json:"server,omitempty"`
+	Evidence string `json:"evidence"`
+}
+
+// proxyUnknown names a proxy that was detected but not recognized
+const proxyUnknown = "unknown"
+
+// detectProxy is nil for responses that look like they came from the real IMDS
+func detectProxy(method string, url string, status int, header http.Header) *metadataProxy {
+	server := header.Get("Server")
+	if server == imdsServer {
+		return nil
+	}
+	lower := strings.ToLower(server)
+	for _, p := range knownProxies {
+		if strings.Contains(lower, p.server) {
+			return &metadataProxy{Name: p.name, Server: server, Evidence: "Server header"}
+		}
+	}
+	if method == http.MethodPut && strings.HasSuffix(url, tokenPath) &&
+		(status == http.StatusNotFound || status == http.StatusMethodNotAllowed) {
+		return &metadataProxy{Name: proxyUnknown, Server: server,
+			Evidence: "token endpoint answered " + strconv.Itoa(status)}
+	}
+	if server != "" {
+		return &metadataProxy{Name: proxyUnknown, Server: server, Evidence: "Server header is not " + imdsServer}
+	}
+	return &metadataProxy{Name: proxyUnknown, Evidence: "no Server header"}
+}
+
+// recordProxy keeps the most specific proxy detected: a recognized one over an unknown one
+func (e *Expvar) recordProxy(method string, url string, status int, header http.Header) {
+	detected := detectProxy(method, url, status, header)
+	if detected == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.metadataProxy == nil || (e.metadataProxy.Name == proxyUnknown && detected.Name != proxyUnknown) {
+		e.metadataProxy = detected
+	}
+}
+
+// metadataProxyOf is nil until an IMDS response gives a proxy away
+func (e *Expvar) metadataProxyOf() interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.metadataProxy == nil {
+		return nil
+	}
+	return *e.metadataProxy
+}
@@ -0,0 +1,115 @@
+package awsexpvar
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Public IPv4 types reported under "public-ip"
+const (
+	PublicIPElastic   = "elastic"
+	PublicIPEphemeral = "ephemeral"
+)
+
+// publicIP classifies the public IPv4 of the primary interface.  Elastic IPs show up in the interface's
+// ipv4-associations, ephemeral ones do not, and only an elastic IP survives a stop/start.
+func publicIP(metaData interface{}) interface{} {
+	ip := lookupString(metaData, "public-ipv4")
+	if ip == "" {
+		return nil
+	}
+	ipType := PublicIPEphemeral
+	mac := lookupString(metaData, "mac")
+	for _, associated := range children(metaData, "network", "interfaces", "macs", mac, "ipv4-associations") {
+		if associated == ip {
+			ipType = PublicIPElastic
+		}
+	}
+	return map[string]string{
+		"address": ip,
+		"type":    ipType,
+	}
+}
+
+type networkInterface struct {
+	MAC           string `json:"mac"`
+	InterfaceID   string `json:"interface-id,omitempty"`
+	DeviceNumber  string `json:"device-number,omitempty"`
+	NetworkCard   string `json:"network-card,omitempty"`
+	SubnetID      string `json:"subnet-id,omitempty"`
+	VPCID         string `json:"vpc-id,omitempty"`
+	LocalIPv4s    string `json:"local-ipv4s,omitempty"`
+	PrimaryDevice bool   `json:"primary"`
+}
+
+// interfaces summarizes every ENI, ordered by device number, so NAT and router style workloads can check their
+// interface layout at runtime.  IMDS does not publish the source/dest check flag; that needs ec2:DescribeNetworkInterfaces.
+func interfaces(metaData interface{}) interface{} {
+	macs := children(metaData, "network", "interfaces", "macs")
+	if len(macs) == 0 {
+		return nil
+	}
+	primary := lookupString(metaData, "mac")
+	ret := make([]networkInterface, 0, len(macs))
+	for _, mac := range macs {
+		field := func(name string) string {
+			return lookupString(metaData, "network", "interfaces", "macs", mac, name)
+		}
+		ret = append(ret, networkInterface{
+			MAC:           mac,
+			InterfaceID:   field("interface-id"),
+			DeviceNumber:  field("device-number"),
+			NetworkCard:   field("network-card"),
+			SubnetID:      field("subnet-id"),
+			VPCID:         field("vpc-id"),
+			LocalIPv4s:    field("local-ipv4s"),
+			PrimaryDevice: mac == primary,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return deviceOrder(ret[i].DeviceNumber) < deviceOrder(ret[j].DeviceNumber)
+	})
+	return ret
+}
+
+func deviceOrder(deviceNumber string) int {
+	n, err := strconv.Atoi(deviceNumber)
+	if err != nil {
+		return math.MaxInt32
+	}
+	return n
+}
+
+// networkCounts are the gauges capacity planning for IP-per-task networking needs.  Secondary IPs are the private
+// IPv4 addresses of every ENI beyond its primary one, and security groups are counted once however many ENIs use them.
+func networkCounts(metaData interface{}) map[string]interface{} {
+	macs := children(metaData, "network", "interfaces", "macs")
+	if len(macs) == 0 {
+		return nil
+	}
+	secondaryIPs := 0
+	groups := make(map[string]struct{})
+	for _, mac := range macs {
+		if ips := lines(lookupString(metaData, "network", "interfaces", "macs", mac, "local-ipv4s")); len(ips) > 1 {
+			secondaryIPs += len(ips) - 1
+		}
+		for _, group := range lines(lookupString(metaData, "network", "interfaces", "macs", mac, "security-group-ids")) {
+			groups[group] = struct{}{}
+		}
+	}
+	return map[string]interface{}{
+		"eni-count":            len(macs),
+		"secondary-ip-count":   secondaryIPs,
+		"security-group-count": len(groups),
+	}
+}
+
+// lines splits an IMDS list, one entry per line
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
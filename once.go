@@ -0,0 +1,111 @@
+package awsexpvar
+
+import "context"
+
+// fieldFlight is one resolution of a field by FetchOnce, which concurrent calls for that field wait on instead of
+// each asking IMDS
+type fieldFlight struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// FetchOnce resolves field at most once per Expvar and memoizes it, for values that cannot change during the
+// lifetime of an instance or task.  Failures are not memoized, so a later call retries.  Concurrent calls for one
+// field share a single resolution, and calls for other fields do not wait on it.
+func (e *Expvar) FetchOnce(ctx context.Context, field Field) (string, error) {
+	e.onceMu.Lock()
+	if val, exists := e.onceValues[field]; exists {
+		e.onceMu.Unlock()
+		return val, nil
+	}
+	if f := e.onceFlights[field]; f != nil {
+		e.onceMu.Unlock()
+		select {
+		case <-f.done:
+			return f.value, f.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	f := &fieldFlight{done: make(chan struct{})}
+	if e.onceFlights == nil {
+		e.onceFlights = make(map[Field]*fieldFlight)
+	}
+	e.onceFlights[field] = f
+	e.onceMu.Unlock()
+	vals, err := e.Fetch(ctx, field)
+	f.value, f.err = vals[field], err
+	e.onceMu.Lock()
+	delete(e.onceFlights, field)
+	if err == nil {
+		if e.onceValues == nil {
+			e.onceValues = make(map[Field]string)
+		}
+		e.onceValues[field] = f.value
+	}
+	e.onceMu.Unlock()
+	close(f.done)
+	return f.value, f.err
+}
+
+// InstanceIDOnce is FetchOnce(ctx, FieldInstanceID)
+func (e *Expvar) InstanceIDOnce(ctx context.Context) (string, error) {
+	return e.FetchOnce(ctx, FieldInstanceID)
+}
+
+// InstanceTypeOnce is FetchOnce(ctx, FieldInstanceType)
+func (e *Expvar) InstanceTypeOnce(ctx context.Context) (string, error) {
+	return e.FetchOnce(ctx, FieldInstanceType)
+}
+
+// AMIIDOnce is FetchOnce(ctx, FieldAMIID)
+func (e *Expvar) AMIIDOnce(ctx context.Context) (string, error) {
+	return e.FetchOnce(ctx, FieldAMIID)
+}
+
+// AvailabilityZoneOnce is FetchOnce(ctx, FieldAvailabilityZone)
+func (e *Expvar) AvailabilityZoneOnce(ctx context.Context) (string, error) {
+	return e.FetchOnce(ctx, FieldAvailabilityZone)
+}
+
+// RegionOnce is FetchOnce(ctx, FieldRegion)
+func (e *Expvar) RegionOnce(ctx context.Context) (string, error) {
+	return e.FetchOnce(ctx, FieldRegion)
+}
+
+// AccountIDOnce is FetchOnce(ctx, FieldAccountID)
+func (e *Expvar) AccountIDOnce(ctx context.Context) (string, error) {
+	return e.FetchOnce(ctx, FieldAccountID)
+}
+
+// TaskARNOnce is FetchOnce(ctx, FieldTaskARN)
+func (e *Expvar) TaskARNOnce(ctx context.Context) (string, error) {
+	return e.FetchOnce(ctx, FieldTaskARN)
+}
+
+// InstanceID is InstanceIDOnce without a context, for tagging logs and metrics at startup.  Each request is still
+// bounded by Timeout.
+func (e *Expvar) InstanceID() (string, error) {
+	return e.InstanceIDOnce(context.Background())
+}
+
+// InstanceType is InstanceTypeOnce without a context
+func (e *Expvar) InstanceType() (string, error) {
+	return e.InstanceTypeOnce(context.Background())
+}
+
+// AMIID is AMIIDOnce without a context
+func (e *Expvar) AMIID() (string, error) {
+	return e.AMIIDOnce(context.Background())
+}
+
+// AvailabilityZone is AvailabilityZoneOnce without a context
+func (e *Expvar) AvailabilityZone() (string, error) {
+	return e.AvailabilityZoneOnce(context.Background())
+}
+
+// Region is RegionOnce without a context
+func (e *Expvar) Region() (string, error) {
+	return e.RegionOnce(context.Background())
+}
@@ -0,0 +1,73 @@
+package awsexpvar
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingIMDS is mapIMDS, except that paths ending in block wait for release
+type blockingIMDS struct {
+	mapIMDS
+	block    string
+	release  chan struct{}
+	requests int64
+}
+
+func (s *blockingIMDS) Get(ctx context.Context, path string) ([]byte, error) {
+	if strings.HasSuffix(path, s.block) {
+		atomic.AddInt64(&s.requests, 1)
+		<-s.release
+	}
+	return s.mapIMDS.Get(ctx, path)
+}
+
+func TestFetchOnceConcurrent(t *testing.T) {
+	imds := &blockingIMDS{
+		mapIMDS: mapIMDS{
+			metadataPath + "instance-id":   "i-0123456789abcdef0",
+			metadataPath + "instance-type": "m5.large",
+		},
+		block:   "instance-id",
+		release: make(chan struct{}),
+	}
+	e := &Expvar{IMDS: imds}
+	var wg sync.WaitGroup
+	ids := make([]string, 3)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], _ = e.InstanceID()
+		}(i)
+	}
+	for atomic.LoadInt64(&imds.requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	typed := make(chan string, 1)
+	go func() {
+		instanceType, _ := e.InstanceType()
+		typed <- instanceType
+	}()
+	select {
+	case instanceType := <-typed:
+		if instanceType != "m5.large" {
+			t.Errorf("instance type %q", instanceType)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("instance type waited on the instance ID request")
+	}
+	close(imds.release)
+	wg.Wait()
+	for _, id := range ids {
+		if id != "i-0123456789abcdef0" {
+			t.Errorf("instance ID %q", id)
+		}
+	}
+	if n := atomic.LoadInt64(&imds.requests); n != 1 {
+		t.Errorf("%d instance ID requests, want concurrent callers sharing one", n)
+	}
+}
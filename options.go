@@ -0,0 +1,96 @@
+package awsexpvar
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures an Expvar made by New.  Each sets the field of the same name, so an Expvar built as a struct
+// literal keeps working the same way.
+type Option func(*Expvar)
+
+// New returns an Expvar with opts applied in order
+func New(opts ...Option) *Expvar {
+	e := &Expvar{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// WithClient sets Client
+func WithClient(client *http.Client) Option {
+	return func(e *Expvar) {
+		e.Client = client
+	}
+}
+
+// WithLogger sets Log
+func WithLogger(log Logger) Option {
+	return func(e *Expvar) {
+		e.Log = log
+	}
+}
+
+// WithTimeout sets Timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(e *Expvar) {
+		e.Timeout = timeout
+	}
+}
+
+// WithSourcesDisabled adds sections, such as "user-data", to DisabledSections
+func WithSourcesDisabled(sections ...string) Option {
+	return func(e *Expvar) {
+		e.DisabledSections = append(e.DisabledSections, sections...)
+	}
+}
+
+// WithRedactKeys adds key names, such as "Signature", to RedactKeys
+func WithRedactKeys(keys ...string) Option {
+	return func(e *Expvar) {
+		e.RedactKeys = append(e.RedactKeys, keys...)
+	}
+}
+
+// WithMetadataEndpoints sets MetadataEndpoints
+func WithMetadataEndpoints(endpoints ...string) Option {
+	return func(e *Expvar) {
+		e.MetadataEndpoints = endpoints
+	}
+}
+
+// WithUserAgent sets UserAgent
+func WithUserAgent(userAgent string) Option {
+	return func(e *Expvar) {
+		e.UserAgent = userAgent
+	}
+}
+
+// WithIMDS sets IMDS
+func WithIMDS(client IMDSClient) Option {
+	return func(e *Expvar) {
+		e.IMDS = client
+	}
+}
+
+// WithCacheTTL sets CacheTTL
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(e *Expvar) {
+		e.CacheTTL = ttl
+	}
+}
+
+// WithRefreshInterval sets RefreshInterval
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(e *Expvar) {
+		e.RefreshInterval = interval
+	}
+}
+
+// WithConfig applies c, as Config.Apply does
+func WithConfig(c *Config) Option {
+	return func(e *Expvar) {
+		c.Apply(e)
+	}
+}
@@ -0,0 +1,96 @@
+// Package oteldetector is an OpenTelemetry resource.Detector built on awsexpvar, so tracing and metrics pipelines
+// describe this process with the same metadata the expvar output does.  It is its own module so the OpenTelemetry
+// SDK is only a dependency of programs that want it.
+//
+//	res, err := resource.New(ctx, resource.WithDetectors(oteldetector.New(e)))
+package oteldetector
+
+import (
+	"context"
+
+	"github.com/cep21/awsexpvar"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+)
+
+// Detector maps instance and task metadata to semantic convention resource attributes.  It resolves only the fields
+// it maps, with Expvar.Fetch, and skips IMDS entirely where there is none.
+type Detector struct {
+	Expvar *awsexpvar.Expvar
+}
+
+var _ resource.Detector = &Detector{}
+
+// New returns a Detector of e
+func New(e *awsexpvar.Expvar) *Detector {
+	return &Detector{Expvar: e}
+}
+
+var instanceFields = []awsexpvar.Field{awsexpvar.FieldInstanceID, awsexpvar.FieldInstanceType,
+	awsexpvar.FieldAMIID, awsexpvar.FieldAvailabilityZone, awsexpvar.FieldRegion, awsexpvar.FieldAccountID}
+
+var taskFields = []awsexpvar.Field{awsexpvar.FieldTaskARN, awsexpvar.FieldCluster, awsexpvar.FieldTaskFamily,
+	awsexpvar.FieldTaskRevision}
+
+// Detect returns an empty resource outside of AWS.  Fields that could not be resolved are left out rather than
+// failing the detection.
+func (d *Detector) Detect(ctx context.Context) (*resource.Resource, error) {
+	var fields []awsexpvar.Field
+	onInstance := !awsexpvar.IsLambda() && !awsexpvar.IsFargate()
+	if onInstance {
+		fields = append(fields, instanceFields...)
+	}
+	if awsexpvar.IsECS() {
+		fields = append(fields, taskFields...)
+	}
+	if len(fields) == 0 {
+		return resource.Empty(), nil
+	}
+	vals, _ := d.Expvar.Fetch(ctx, fields...)
+	attrs := make([]attribute.KeyValue, 0, 16)
+	add := func(field awsexpvar.Field, attr func(string) attribute.KeyValue) {
+		if val := vals[field]; val != "" {
+			attrs = append(attrs, attr(val))
+		}
+	}
+	add(awsexpvar.FieldInstanceID, semconv.HostID)
+	add(awsexpvar.FieldInstanceType, semconv.HostType)
+	add(awsexpvar.FieldAMIID, semconv.HostImageID)
+	add(awsexpvar.FieldAvailabilityZone, semconv.CloudAvailabilityZone)
+	add(awsexpvar.FieldTaskARN, semconv.AWSECSTaskARN)
+	add(awsexpvar.FieldTaskFamily, semconv.AWSECSTaskFamily)
+	add(awsexpvar.FieldTaskRevision, semconv.AWSECSTaskRevision)
+	region, account := vals[awsexpvar.FieldRegion], vals[awsexpvar.FieldAccountID]
+	if task, err := awsexpvar.ParseARN(vals[awsexpvar.FieldTaskARN]); err == nil {
+		// Fargate has no identity document, but the task ARN says where the task runs
+		if region == "" {
+			region = task.Region
+		}
+		if account == "" {
+			account = task.AccountID
+		}
+	}
+	if region != "" {
+		attrs = append(attrs, semconv.CloudRegion(region))
+	}
+	if account != "" {
+		attrs = append(attrs, semconv.CloudAccountID(account))
+	}
+	if cluster, err := awsexpvar.ParseARN(vals[awsexpvar.FieldCluster]); err == nil {
+		attrs = append(attrs, semconv.AWSECSClusterARN(cluster.String()))
+	}
+	switch {
+	case awsexpvar.IsFargate():
+		attrs = append(attrs, semconv.CloudPlatformAWSECS, semconv.AWSECSLaunchtypeFargate)
+	case awsexpvar.IsECS():
+		attrs = append(attrs, semconv.CloudPlatformAWSECS, semconv.AWSECSLaunchtypeEC2)
+	case vals[awsexpvar.FieldInstanceID] != "":
+		attrs = append(attrs, semconv.CloudPlatformAWSEC2)
+	}
+	if len(attrs) == 0 {
+		return resource.Empty(), nil
+	}
+	attrs = append(attrs, semconv.CloudProviderAWS)
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
@@ -0,0 +1,78 @@
+package awsexpvar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// dumpTimeout bounds the crawl made when a crash dump is requested before any snapshot was taken
+const dumpTimeout = time.Second
+
+// WriteSnapshot writes the latest snapshot to w as indented JSON, for postmortems.  It is safe to call from a crash
+// handler: if Refresh was never called it crawls for at most one second.
+func (e *Expvar) WriteSnapshot(w io.Writer) error {
+	if snap := e.snapshot(); snap != nil {
+		return render(w, snap.withAge(time.Now()), FormatIndentedJSON)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dumpTimeout)
+	defer cancel()
+	return render(w, e.fetch(ctx), FormatIndentedJSON)
+}
+
+// DumpOnPanic writes the latest snapshot to w if the goroutine is panicking, then continues the panic.  Defer it
+// directly at the top of main or of a goroutine:
+//
+//	defer e.DumpOnPanic(os.Stderr)
+func (e *Expvar) DumpOnPanic(w io.Writer) {
+	if r := recover(); r != nil {
+		if _, err := fmt.Fprintf(w, "panic: %v\naws metadata at time of panic:\n", r); err == nil {
+			_ = e.WriteSnapshot(w)
+		}
+		panic(r)
+	}
+}
+
+// DumpOnPanicToFile is DumpOnPanic writing to the file at path.  When path cannot be written, as on a read-only root
+// filesystem, the dump goes to stderr instead, preceded by a line saying why, unless FileCipher is set.
+//
+//	defer e.DumpOnPanicToFile("/var/log/app/crash-metadata.json")
+func (e *Expvar) DumpOnPanicToFile(path string) {
+	if r := recover(); r != nil {
+		if err := e.writeSnapshotFile(path); err != nil {
+			if e.Log != nil {
+				e.Log.Log("err", err, "path", path, "unable to write crash dump")
+			}
+			if e.FileCipher != nil {
+				// never fall back to writing in the clear what was meant to be encrypted
+				panic(r)
+			}
+			if _, err := fmt.Fprintf(os.Stderr, "crash dump status: %s: %v\npanic: %v\naws metadata at time of panic:\n", FileWritesDegraded, err, r); err == nil {
+				_ = e.WriteSnapshot(os.Stderr)
+			}
+		}
+		panic(r)
+	}
+}
+
+// writeSnapshotFile encrypts the snapshot with FileCipher, when set, before it touches the disk
+func (e *Expvar) writeSnapshotFile(path string) error {
+	var buf bytes.Buffer
+	if err := e.WriteSnapshot(&buf); err != nil {
+		return err
+	}
+	contents := buf.Bytes()
+	if e.FileCipher != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), dumpTimeout)
+		defer cancel()
+		var err error
+		if contents, err = e.FileCipher.Encrypt(ctx, contents); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(path, contents, 0600)
+}
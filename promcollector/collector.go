@@ -0,0 +1,54 @@
+// Package promcollector exports awsexpvar metadata as Prometheus info metrics, for programs scraped by Prometheus
+// rather than read through expvar.  It is its own module so the Prometheus client is only a dependency of programs
+// that want it.
+//
+//	e := &awsexpvar.Expvar{CacheTTL: time.Minute}
+//	prometheus.MustRegister(promcollector.New(e))
+package promcollector
+
+import (
+	"context"
+
+	"github.com/cep21/awsexpvar"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	instanceInfo = prometheus.NewDesc("aws_instance_info", "EC2 instance this process runs on. Always 1.",
+		[]string{"instance_id", "instance_type", "az", "ami", "region", "account_id"}, nil)
+	taskInfo = prometheus.NewDesc("aws_ecs_task_info", "ECS task this process runs in. Always 1.",
+		[]string{"cluster", "task_arn", "family", "revision", "launch_type"}, nil)
+)
+
+// Collector builds its metrics from Expvar.Snapshot on every scrape, so it serves the refreshed or CacheTTL snapshot
+// when Expvar has one instead of crawling each time.  Each metric is only exported where it applies: there is no
+// instance on Fargate, and no task outside ECS.
+type Collector struct {
+	Expvar *awsexpvar.Expvar
+}
+
+var _ prometheus.Collector = &Collector{}
+
+// New returns a Collector of e
+func New(e *awsexpvar.Expvar) *Collector {
+	return &Collector{Expvar: e}
+}
+
+// Describe sends both info metrics
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- instanceInfo
+	ch <- taskInfo
+}
+
+// Collect sends the info metrics that apply here
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	slim := c.Expvar.Snapshot(context.Background()).Slim()
+	if slim["instanceId"] != "" {
+		ch <- prometheus.MustNewConstMetric(instanceInfo, prometheus.GaugeValue, 1, slim["instanceId"],
+			slim["instanceType"], slim["availabilityZone"], slim["imageId"], slim["region"], slim["accountId"])
+	}
+	if slim["taskArn"] != "" {
+		ch <- prometheus.MustNewConstMetric(taskInfo, prometheus.GaugeValue, 1, slim["cluster"], slim["taskArn"],
+			slim["taskFamily"], slim["taskRevision"], slim["launchType"])
+	}
+}
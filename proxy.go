@@ -0,0 +1,184 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// proxyRoots are the IMDS trees Handler proxies sub-paths into, each with the section that exposes it
+var proxyRoots = map[string]string{
+	"meta-data": "meta-data",
+	"dynamic":   "instance-identity",
+	"user-data": "user-data",
+}
+
+var errCredentialPath = errors.New("instance role credentials are never proxied")
+
+// errProxyPathSegment refuses paths that could step outside the tree they name, which a file:// endpoint would follow
+var errProxyPathSegment = errors.New("proxied paths may not contain empty, . or .. segments")
+
+// proxiedLeaf is a proxied IMDS body, kept for CacheTTL
+type proxiedLeaf struct {
+	body    []byte
+	takenAt time.Time
+}
+
+// proxyPath finds the IMDS path a Handler request names, such as "meta-data/placement/availability-zone" out of
+// "/debug/aws/meta-data/placement/availability-zone", wherever the Handler is mounted.  The path is not checked; see
+// checkProxyPath.
+func proxyPath(urlPath string) ([]string, bool) {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	for i, segment := range segments {
+		if _, exists := proxyRoots[segment]; exists {
+			return segments[i:], true
+		}
+	}
+	return nil, false
+}
+
+// checkProxyPath refuses paths with empty, "." or ".." segments
+func checkProxyPath(path []string) error {
+	for _, segment := range path {
+		switch segment {
+		case "", ".", "..":
+			return errProxyPathSegment
+		}
+	}
+	return nil
+}
+
+// proxy fetches one IMDS path with the same redactions as the crawl: disabled sections and instance role credentials
+// are refused, credential fields are removed, and leaves go through Transform.  Bodies are reused for CacheTTL.
+func (e *Expvar) proxy(ctx context.Context, path []string) ([]byte, error) {
+	if err := checkProxyPath(path); err != nil {
+		return nil, err
+	}
+	if e.sectionDisabled(proxyRoots[path[0]]) {
+		return nil, errDisabled
+	}
+	for _, segment := range path {
+		if segment == "security-credentials" {
+			return nil, errCredentialPath
+		}
+	}
+	key := strings.Join(path, "/")
+	now := time.Now()
+	e.mu.Lock()
+	leaf, exists := e.proxied[key]
+	e.mu.Unlock()
+	if exists && now.Sub(leaf.takenAt) < e.CacheTTL {
+		return leaf.body, nil
+	}
+	b, err := e.imdsGet(ctx, "/latest/"+key)
+	if err != nil {
+		return nil, err
+	}
+	b = e.redactProxied(path, b)
+	if e.CacheTTL > 0 {
+		e.mu.Lock()
+		if e.proxied == nil {
+			e.proxied = make(map[string]proxiedLeaf)
+		}
+		e.proxied[key] = proxiedLeaf{body: b, takenAt: now}
+		e.mu.Unlock()
+	}
+	return b, nil
+}
+
+// redactProxied applies to one body what single, processParts and applyTransforms apply to the crawl
+func (e *Expvar) redactProxied(path []string, b []byte) []byte {
+	for _, p := range e.ScrubPatterns {
+		b = p.ReplaceAllLiteral(b, []byte(redactedValue))
+	}
+	var doc map[string]interface{}
+	if err := decodeJSON(b, &doc); err == nil {
+		removed := false
+		redactTree(doc, e.redactedKeys(), "", func(string) { removed = true })
+		if removed {
+			if redacted, err := json.Marshal(doc); err == nil {
+				return redacted
+			}
+		}
+		return b
+	}
+	if lines := strings.Split(string(b), "\n"); containsString(lines, "security-credentials/") {
+		kept := make([]string, 0, len(lines))
+		for _, line := range lines {
+			if line != "security-credentials/" {
+				kept = append(kept, line)
+			}
+		}
+		return []byte(strings.Join(kept, "\n"))
+	}
+	e.mu.Lock()
+	transforms := e.transforms
+	e.mu.Unlock()
+	for _, t := range transforms {
+		if samePath(t.path, path) {
+			b = []byte(t.fn(strings.TrimSpace(string(b))))
+		}
+	}
+	return b
+}
+
+// samePath matches two paths segment by segment, ignoring the trailing slash of directories
+func samePath(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if strings.TrimSuffix(a[i], "/") != strings.TrimSuffix(b[i], "/") {
+			return false
+		}
+	}
+	return true
+}
+
+// serveProxy writes one proxied IMDS body as text
+func (h *Handler) serveProxy(w http.ResponseWriter, r *http.Request, path []string) {
+	if err := checkProxyPath(path); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.proxyAllowed(path) {
+		http.Error(w, "path not in proxy safelist", http.StatusForbidden)
+		return
+	}
+	b, err := h.Expvar.proxy(r.Context(), path)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNotFound), errors.Is(err, errDisabled):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errCredentialPath):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, errProxyPathSegment):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := w.Write(b); err != nil && h.Expvar.Log != nil {
+		h.Expvar.Log.Log("err", err, "unable to write proxied metadata")
+	}
+}
+
+// proxyAllowed is true for every path unless ProxySafelist is set, and then only for the paths it lists
+func (h *Handler) proxyAllowed(path []string) bool {
+	if h.ProxySafelist == nil {
+		return true
+	}
+	for _, allowed := range h.ProxySafelist {
+		if samePath(strings.Split(strings.Trim(allowed, "/"), "/"), path) {
+			return true
+		}
+	}
+	return false
+}
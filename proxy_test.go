@@ -0,0 +1,39 @@
+package awsexpvar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestHandlerProxy(t *testing.T) {
+	endpoint := fileEndpoint(t, map[string]string{
+		"imds/latest/meta-data/instance-id": "i-0123456789abcdef0",
+		"outside.txt":                       "not metadata",
+	})
+	h := &Handler{Expvar: &Expvar{MetadataEndpoints: []string{endpoint + "/imds"}}}
+	for _, tc := range []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{path: "/debug/aws/meta-data/instance-id", status: http.StatusOK, body: "i-0123456789abcdef0"},
+		{path: "/debug/aws/meta-data/../../../outside.txt", status: http.StatusBadRequest},
+		{path: "/debug/aws/meta-data/./instance-id", status: http.StatusBadRequest},
+		{path: "/debug/aws/meta-data//instance-id", status: http.StatusBadRequest},
+		{path: "/debug/aws/meta-data/iam/security-credentials/role", status: http.StatusForbidden},
+		{path: "/debug/aws/meta-data/missing", status: http.StatusNotFound},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, &http.Request{Method: http.MethodGet, URL: &url.URL{Path: tc.path}})
+			if rec.Code != tc.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tc.status, rec.Body.String())
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Fatalf("body %q, want %q", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
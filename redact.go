@@ -0,0 +1,94 @@
+package awsexpvar
+
+import (
+	"context"
+	"expvar"
+	"strconv"
+	"strings"
+)
+
+// RedactedVar is Var with each of paths, such as "user-data" or "meta-data/public-keys", removed as it is published.
+// Paths are written the way Transform takes them.  Several vars built from one Expvar share its refreshed snapshot
+// (see Refresh), so a redacted var on the public debug mux and the full output on a localhost admin mux cost one set
+// of metadata requests:
+//
+//	expvar.Publish("aws", e.RedactedVar("user-data", "env"))
+//	adminMux.Handle("/aws_full", &awsexpvar.Handler{Expvar: e})
+//
+// Changes under a removed path are left out of "history" too.  Credentials are never published, whichever var is
+// used.
+func (e *Expvar) RedactedVar(paths ...string) expvar.Var {
+	split := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		split = append(split, strings.Split(strings.Trim(path, "/"), "/"))
+	}
+	return expvar.Func(func() interface{} {
+		// current decodes a private copy of the snapshot, so removing from it leaves other vars alone
+		ret := e.current(context.Background())
+		for _, path := range split {
+			removePath(ret, path)
+		}
+		dropHistory(ret, split)
+		return ret
+	})
+}
+
+// DefaultRedactKeys are always removed from the output, whatever RedactKeys says
+var DefaultRedactKeys = []string{"Token", "AccessKeyId", "SecretAccessKey"}
+
+// redactedValue replaces every redacted value
+const redactedValue = "(removed)"
+
+func (e *Expvar) redactedKeys() []string {
+	return append(append([]string(nil), DefaultRedactKeys...), e.RedactKeys...)
+}
+
+// redactKeys removes the values of redactedKeys from every section of ret, at any depth
+func (e *Expvar) redactKeys(ctx context.Context, ret map[string]interface{}) {
+	redactTree(ret, e.redactedKeys(), "", func(path string) {
+		noteRedaction(ctx, path, "redacted key")
+	})
+}
+
+// redactTree replaces, in place, the values of keys in the maps of tree, calling removed with the path of each
+func redactTree(tree interface{}, keys []string, prefix string, removed func(path string)) {
+	join := func(key string) string {
+		key = strings.TrimSuffix(key, "/")
+		if prefix == "" {
+			return key
+		}
+		return prefix + "/" + key
+	}
+	switch val := tree.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if matchesKey(k, keys) {
+				val[k] = redactedValue
+				removed(join(k))
+				continue
+			}
+			redactTree(child, keys, join(k), removed)
+		}
+	case map[string]string:
+		for k := range val {
+			if matchesKey(k, keys) {
+				val[k] = redactedValue
+				removed(join(k))
+			}
+		}
+	case []interface{}:
+		for i, child := range val {
+			redactTree(child, keys, join(strconv.Itoa(i)), removed)
+		}
+	}
+}
+
+func matchesKey(key string, keys []string) bool {
+	key = strings.TrimSuffix(key, "/")
+	for _, k := range keys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package awsexpvar
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRedactedVar(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	endpoint := fileEndpoint(t, map[string]string{
+		"latest/user-data":                           "secret-one",
+		"latest/meta-data/instance-id":               "i-0123456789abcdef0",
+		"latest/meta-data/public-keys/0/openssh-key": "key-one",
+	})
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{endpoint}}
+	redacted := e.RedactedVar("user-data", "/meta-data/public-keys/").String()
+	for _, secret := range []string{"secret-one", "key-one"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("redacted value %q published in %s", secret, redacted)
+		}
+	}
+	if !strings.Contains(redacted, "i-0123456789abcdef0") {
+		t.Errorf("instance-id missing from %s", redacted)
+	}
+	if full := e.Var().String(); !strings.Contains(full, "secret-one") || !strings.Contains(full, "key-one") {
+		t.Errorf("redacting one var changed the full var: %s", full)
+	}
+}
+
+func TestRedactKeys(t *testing.T) {
+	tree := map[string]interface{}{
+		"meta-data": map[string]interface{}{
+			"iam/": map[string]interface{}{"info": map[string]string{"AccessKeyId": "AKID", "Code": "Success"}},
+		},
+		"task-metadata": map[string]interface{}{
+			"Containers": []interface{}{map[string]interface{}{"Signature": "abc", "Name": "app"}},
+		},
+	}
+	var removed []string
+	keys := (&Expvar{RedactKeys: []string{"signature"}}).redactedKeys()
+	redactTree(tree, keys, "", func(path string) { removed = append(removed, path) })
+	sort.Strings(removed)
+	want := []string{"meta-data/iam/info/AccessKeyId", "task-metadata/Containers/0/Signature"}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("removed %v, want %v", removed, want)
+	}
+	info := lookup(tree, "meta-data", "iam", "info").(map[string]string)
+	if info["AccessKeyId"] != redactedValue || info["Code"] != "Success" {
+		t.Errorf("info %v", info)
+	}
+	container := lookup(tree, "task-metadata", "Containers").([]interface{})[0].(map[string]interface{})
+	if container["Signature"] != redactedValue || container["Name"] != "app" {
+		t.Errorf("container %v", container)
+	}
+}
+
+func TestRedactedVarHistory(t *testing.T) {
+	endpoint := fileEndpoint(t, map[string]string{
+		"latest/user-data":                             "secret-one",
+		"latest/meta-data/instance-id":                 "i-0123456789abcdef0",
+		"latest/meta-data/public-keys/0/openssh-key":   "key-one",
+		"latest/meta-data/placement/availability-zone": "us-west-2a",
+	})
+	e := &Expvar{MetadataEndpoints: []string{endpoint}}
+	v := e.RedactedVar("user-data", "meta-data/public-keys")
+	if got := v.String(); strings.Contains(got, "secret-one") || strings.Contains(got, "key-one") {
+		t.Fatalf("redacted value published: %s", got)
+	}
+	dir := strings.TrimPrefix(endpoint, "file://")
+	for path, body := range map[string]string{
+		"latest/user-data":                             "secret-two",
+		"latest/meta-data/public-keys/0/openssh-key":   "key-two",
+		"latest/meta-data/placement/availability-zone": "us-west-2b",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(path)), []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := v.String()
+	for _, secret := range []string{"secret-one", "secret-two", "key-one", "key-two"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("redacted value %q published in %s", secret, got)
+		}
+	}
+	if !strings.Contains(got, `"meta-data/placement/availability-zone"`) {
+		t.Errorf("change outside the redacted paths missing from history: %s", got)
+	}
+}
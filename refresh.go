@@ -0,0 +1,170 @@
+package awsexpvar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// errNoSources is returned by Refresh when not a single metadata source answered
+var errNoSources = errors.New("no metadata source answered")
+
+// cachedSnapshot is kept marshalled: one flat byte slice instead of a deep tree of maps for the garbage collector to
+// scan for as long as the snapshot lives
+type cachedSnapshot struct {
+	encoded     json.RawMessage
+	takenAt     time.Time
+	nextRefresh time.Time
+}
+
+// Refresh re-fetches every metadata source now and stores the result.  From then on Var serves the stored snapshot
+// instead of crawling on every evaluation, so operators (from an admin endpoint) or the application (on SIGHUP) can
+// control exactly when metadata is re-read.  The snapshot is stored even if no source answered or ctx ended.
+// Stored snapshots are published with "snapshot_taken_at" and "age_seconds" keys, and with "next_refresh_at" while
+// Start keeps them refreshed.
+func (e *Expvar) Refresh(ctx context.Context) error {
+	values := e.fetch(ctx)
+	if err := e.store(values); err != nil {
+		return err
+	}
+	return fetchErr(ctx, values)
+}
+
+// store makes values the snapshot Var serves
+func (e *Expvar) store(values map[string]interface{}) error {
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cached = &cachedSnapshot{
+		encoded:     encoded,
+		takenAt:     time.Now(),
+		nextRefresh: e.nextRefresh,
+	}
+	return nil
+}
+
+// fetchErr describes a fetch that ended with ctx, or that no source answered
+func fetchErr(ctx context.Context, values map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if caps, ok := values["capabilities"].([]string); ok && len(caps) == 1 && caps[0] == CapabilityNone {
+		return errNoSources
+	}
+	return nil
+}
+
+// current is the stored snapshot if Refresh was ever called or CacheTTL is set, otherwise a fresh crawl.  It is empty
+// when ctx ends before the crawl does; the crawl is never started again for the same call.
+func (e *Expvar) current(ctx context.Context) map[string]interface{} {
+	ret := make(map[string]interface{})
+	snap, err := e.stored(ctx)
+	if err != nil {
+		return ret
+	}
+	if snap != nil {
+		return snap.withAge(time.Now())
+	}
+	encoded, err := e.sharedFetch(ctx)
+	if err != nil {
+		return ret
+	}
+	_ = decodeJSON(encoded, &ret)
+	return ret
+}
+
+// published is what Var serves: the stored snapshot without decoding it, otherwise a fresh crawl.  Like current, it
+// is empty rather than crawled twice when the shared crawl fails.
+func (e *Expvar) published(ctx context.Context) interface{} {
+	snap, err := e.stored(ctx)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	if snap != nil {
+		return snap.encodedWithAge(time.Now())
+	}
+	encoded, err := e.sharedFetch(ctx)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	return encoded
+}
+
+// stored is the snapshot of the latest Refresh.  Without one, it is a fetch younger than CacheTTL, made now if
+// needed.  It is nil when there is neither, and the error is that of the fetch when making one failed.
+func (e *Expvar) stored(ctx context.Context) (*cachedSnapshot, error) {
+	if snap := e.snapshot(); snap != nil || e.CacheTTL <= 0 {
+		return snap, nil
+	}
+	e.mu.Lock()
+	snap := e.ttlCached
+	e.mu.Unlock()
+	if snap != nil && time.Since(snap.takenAt) < e.CacheTTL {
+		return snap, nil
+	}
+	encoded, err := e.sharedFetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap = &cachedSnapshot{
+		encoded: encoded,
+		takenAt: time.Now(),
+	}
+	e.mu.Lock()
+	e.ttlCached = snap
+	e.mu.Unlock()
+	return snap, nil
+}
+
+func (e *Expvar) snapshot() *cachedSnapshot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cached
+}
+
+// withAge adds when the snapshot was taken, so consumers of a refreshed snapshot can decide whether it is fresh
+// enough for them
+func (c *cachedSnapshot) withAge(now time.Time) map[string]interface{} {
+	ret := make(map[string]interface{})
+	_ = decodeJSON(c.encoded, &ret)
+	ret["snapshot_taken_at"] = c.takenAt
+	ret["age_seconds"] = now.Sub(c.takenAt).Seconds()
+	if !c.nextRefresh.IsZero() {
+		ret["next_refresh_at"] = c.nextRefresh
+	}
+	return ret
+}
+
+// encodedWithAge is withAge spliced into the stored JSON
+func (c *cachedSnapshot) encodedWithAge(now time.Time) json.RawMessage {
+	takenAt, _ := json.Marshal(c.takenAt)
+	var buf bytes.Buffer
+	buf.Grow(len(c.encoded) + 80)
+	buf.WriteString(`{"age_seconds":`)
+	buf.WriteString(strconv.FormatFloat(now.Sub(c.takenAt).Seconds(), 'f', -1, 64))
+	if !c.nextRefresh.IsZero() {
+		nextRefresh, _ := json.Marshal(c.nextRefresh)
+		buf.WriteString(`,"next_refresh_at":`)
+		buf.Write(nextRefresh)
+	}
+	buf.WriteString(`,"snapshot_taken_at":`)
+	buf.Write(takenAt)
+	if len(c.encoded) > 2 {
+		buf.WriteByte(',')
+		buf.Write(c.encoded[1:])
+	} else {
+		buf.WriteByte('}')
+	}
+	return buf.Bytes()
+}
+
+// refreshed is true once Refresh has stored a snapshot
+func (e *Expvar) refreshed() bool {
+	return e.snapshot() != nil
+}
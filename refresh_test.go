@@ -0,0 +1,216 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// taskServer is a task metadata endpoint whose family can change between requests
+type taskServer struct {
+	mu       sync.Mutex
+	family   string
+	requests int64
+}
+
+func (s *taskServer) setFamily(family string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.family = family
+}
+
+func (s *taskServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/task" {
+		http.NotFound(w, r)
+		return
+	}
+	atomic.AddInt64(&s.requests, 1)
+	s.mu.Lock()
+	family := s.family
+	s.mu.Unlock()
+	_, _ = w.Write([]byte(strings.Replace(testTask, `"Family": "web"`, `"Family": "`+family+`"`, 1)))
+}
+
+func serveTask(t *testing.T, family string) *taskServer {
+	t.Helper()
+	s := &taskServer{family: family}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", srv.URL)
+	return s
+}
+
+func publishedFamily(t *testing.T, e *Expvar) string {
+	t.Helper()
+	task, _ := published(t, e)["task-metadata"].(map[string]interface{})
+	family, _ := task["Family"].(string)
+	return family
+}
+
+func TestRefresh(t *testing.T) {
+	s := serveTask(t, "web")
+	e := &Expvar{Client: testClient()}
+	if family := publishedFamily(t, e); family != "web" {
+		t.Fatalf("family %q before Refresh, want web", family)
+	}
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	s.setFamily("api")
+	if family := publishedFamily(t, e); family != "web" {
+		t.Errorf("family %q, want the refreshed web until the next Refresh", family)
+	}
+	if err := e.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if family := publishedFamily(t, e); family != "api" {
+		t.Errorf("family %q after Refresh, want api", family)
+	}
+}
+
+func TestRefreshNoSources(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	t.Setenv("ECS_CONTAINER_METADATA_FILE", "")
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "")
+	e := &Expvar{Client: testClient()}
+	if err := e.Refresh(context.Background()); !errors.Is(err, errNoSources) {
+		t.Errorf("Refresh returned %v, want %v", err, errNoSources)
+	}
+}
+
+// benchmarkValues is a fetch of an instance with many interfaces and a large user-data, as decoded from JSON
+func benchmarkValues(b *testing.B) map[string]interface{} {
+	b.Helper()
+	macs := make(map[string]interface{})
+	for i := 0; i < 15; i++ {
+		leaves := make(map[string]interface{})
+		for _, leaf := range []string{"device-number", "interface-id", "local-hostname", "local-ipv4s", "mac",
+			"owner-id", "security-group-ids", "security-groups", "subnet-id", "subnet-ipv4-cidr-block", "vpc-id",
+			"vpc-ipv4-cidr-block", "vpc-ipv4-cidr-blocks"} {
+			leaves[leaf] = leaf + "-" + strconv.Itoa(i)
+		}
+		macs["0e:00:00:00:00:"+strconv.Itoa(10+i)+"/"] = leaves
+	}
+	values := map[string]interface{}{
+		"meta-data": map[string]interface{}{
+			"ami-id":        "ami-0123456789abcdef0",
+			"instance-id":   "i-0123456789abcdef0",
+			"instance-type": "m5.large",
+			"placement/":    map[string]interface{}{"availability-zone": "us-west-2a", "region": "us-west-2"},
+			"network/":      map[string]interface{}{"interfaces/": map[string]interface{}{"macs/": macs}},
+		},
+		"user-data": strings.Repeat("#!/bin/bash\necho configuring the instance\n", 400),
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ret := make(map[string]interface{})
+	if err := json.Unmarshal(encoded, &ret); err != nil {
+		b.Fatal(err)
+	}
+	return ret
+}
+
+// BenchmarkSnapshotPublish compares publishing a snapshot kept as a map tree, which is marshalled on every scrape,
+// with one kept as a json.RawMessage, which only has its age keys spliced in
+func BenchmarkSnapshotPublish(b *testing.B) {
+	values := benchmarkValues(b)
+	takenAt := time.Now()
+	b.Run("map-tree", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			published := make(map[string]interface{}, len(values)+2)
+			for k, v := range values {
+				published[k] = v
+			}
+			published["snapshot_taken_at"] = takenAt
+			published["age_seconds"] = time.Since(takenAt).Seconds()
+			if _, err := json.Marshal(published); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		b.Fatal(err)
+	}
+	snap := &cachedSnapshot{encoded: encoded, takenAt: takenAt}
+	b.Run("raw-message", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(snap.encodedWithAge(time.Now())); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// retainedSnapshots is how many snapshots BenchmarkSnapshotGC keeps alive, enough for the collector's work on them
+// to stand out from the rest of the heap
+const retainedSnapshots = 100
+
+// BenchmarkSnapshotGC compares the cost of a garbage collection while snapshots are kept as map trees and as
+// json.RawMessages.  Besides ns/op, the time of one collection, it reports heap-objects: the objects the kept
+// snapshots add for the collector to scan.
+func BenchmarkSnapshotGC(b *testing.B) {
+	for _, tc := range []struct {
+		name string
+		keep func(values map[string]interface{}) interface{}
+	}{
+		{
+			name: "map-tree",
+			keep: func(values map[string]interface{}) interface{} {
+				encoded, err := json.Marshal(values)
+				if err != nil {
+					b.Fatal(err)
+				}
+				tree := make(map[string]interface{})
+				if err := json.Unmarshal(encoded, &tree); err != nil {
+					b.Fatal(err)
+				}
+				return tree
+			},
+		},
+		{
+			name: "raw-message",
+			keep: func(values map[string]interface{}) interface{} {
+				encoded, err := json.Marshal(values)
+				if err != nil {
+					b.Fatal(err)
+				}
+				return &cachedSnapshot{encoded: encoded, takenAt: time.Now()}
+			},
+		},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			values := benchmarkValues(b)
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			kept := make([]interface{}, retainedSnapshots)
+			for i := range kept {
+				kept[i] = tc.keep(values)
+			}
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(int64(after.HeapObjects)-int64(before.HeapObjects))/retainedSnapshots, "heap-objects")
+			runtime.KeepAlive(kept)
+			runtime.KeepAlive(values)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package awsexpvar
+
+import (
+	"os"
+	"strings"
+)
+
+type regionWarning struct {
+	Message        string `json:"message"`
+	EnvVar         string `json:"env-var"`
+	EnvRegion      string `json:"env-region"`
+	MetadataRegion string `json:"metadata-region"`
+}
+
+// regionMismatch warns when the region the SDKs will pick up from the environment is not the region the instance is
+// in.  Cross region calls are a common source of odd latency and S3 errors.
+func regionMismatch(identity interface{}) interface{} {
+	metadataRegion := lookupString(identity, "region")
+	if metadataRegion == "" {
+		return nil
+	}
+	for _, envVar := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		envRegion := os.Getenv(envVar)
+		if envRegion == "" {
+			continue
+		}
+		if envRegion == metadataRegion {
+			return nil
+		}
+		return regionWarning{
+			Message:        envVar + " is " + envRegion + " but this instance is in " + metadataRegion,
+			EnvVar:         envVar,
+			EnvRegion:      envRegion,
+			MetadataRegion: metadataRegion,
+		}
+	}
+	return nil
+}
+
+// partition is an AWS partition and the DNS suffix its endpoints live under
+type partition struct {
+	name      string
+	dnsSuffix string
+}
+
+// partitionPrefixes are matched in order, so longer prefixes come before the prefixes they extend
+var partitionPrefixes = []struct {
+	prefix string
+	partition
+}{
+	{prefix: "cn-", partition: partition{name: "aws-cn", dnsSuffix: "amazonaws.com.cn"}},
+	{prefix: "us-gov-", partition: partition{name: "aws-us-gov", dnsSuffix: "amazonaws.com"}},
+	{prefix: "us-isob-", partition: partition{name: "aws-iso-b", dnsSuffix: "sc2s.sgov.gov"}},
+	{prefix: "us-isof-", partition: partition{name: "aws-iso-f", dnsSuffix: "csp.hci.ic.gov"}},
+	{prefix: "us-iso-", partition: partition{name: "aws-iso", dnsSuffix: "c2s.ic.gov"}},
+	{prefix: "eu-isoe-", partition: partition{name: "aws-iso-e", dnsSuffix: "cloud.adc-e.uk"}},
+}
+
+var commercialPartition = partition{name: "aws", dnsSuffix: "amazonaws.com"}
+
+func regionPartition(region string) partition {
+	for _, p := range partitionPrefixes {
+		if strings.HasPrefix(region, p.prefix) {
+			return p.partition
+		}
+	}
+	return commercialPartition
+}
+
+type stsEndpoint struct {
+	Region            string `json:"region"`
+	Partition         string `json:"partition"`
+	RegionalEndpoint  string `json:"regional-endpoint"`
+	RegionalEndpoints string `json:"AWS_STS_REGIONAL_ENDPOINTS,omitempty"`
+}
+
+// stsEndpoints recommends the regional STS endpoint of the region this process runs in, for teams moving off the
+// global sts.amazonaws.com.  AWS_STS_REGIONAL_ENDPOINTS is shown next to it, since "legacy" keeps older SDKs on the
+// global endpoint.
+func stsEndpoints(identity interface{}, task *taskMetadata) interface{} {
+	region := detectedRegion(identity, task)
+	if region == "" {
+		return nil
+	}
+	p := regionPartition(region)
+	return stsEndpoint{
+		Region:            region,
+		Partition:         p.name,
+		RegionalEndpoint:  "sts." + region + "." + p.dnsSuffix,
+		RegionalEndpoints: os.Getenv("AWS_STS_REGIONAL_ENDPOINTS"),
+	}
+}
+
+// detectedRegion is the region of the instance identity document, or of the task ARN off EC2
+func detectedRegion(identity interface{}, task *taskMetadata) string {
+	region := lookupString(identity, "region")
+	if region == "" && task != nil {
+		region = arnRegion(task.TaskARN)
+	}
+	return region
+}
+
+// detectedAccount is the account of the instance identity document, or of the task ARN off EC2
+func detectedAccount(identity interface{}, task *taskMetadata) string {
+	account := lookupString(identity, "accountId")
+	if account == "" && task != nil {
+		if arn, err := ParseARN(task.TaskARN); err == nil {
+			account = arn.AccountID
+		}
+	}
+	return account
+}
+
+type serviceEndpoints struct {
+	Region    string `json:"region"`
+	Partition string `json:"partition"`
+	S3        string `json:"s3"`
+	DynamoDB  string `json:"dynamodb"`
+	STS       string `json:"sts"`
+	ECR       string `json:"ecr"`
+	// ECRRegistry is only known once the account is
+	ECRRegistry string `json:"ecr-registry,omitempty"`
+}
+
+// endpoints are the regional hostnames of the services most processes talk to, for the detected region and
+// partition, so a misrouted client stands out next to what it should be calling
+func endpoints(identity interface{}, task *taskMetadata) interface{} {
+	region := detectedRegion(identity, task)
+	if region == "" {
+		return nil
+	}
+	p := regionPartition(region)
+	ret := serviceEndpoints{
+		Region:    region,
+		Partition: p.name,
+		S3:        "s3." + region + "." + p.dnsSuffix,
+		DynamoDB:  "dynamodb." + region + "." + p.dnsSuffix,
+		STS:       "sts." + region + "." + p.dnsSuffix,
+		ECR:       "api.ecr." + region + "." + p.dnsSuffix,
+	}
+	if account := detectedAccount(identity, task); account != "" {
+		ret.ECRRegistry = account + ".dkr.ecr." + region + "." + p.dnsSuffix
+	}
+	return ret
+}
@@ -0,0 +1,53 @@
+package awsexpvar
+
+import (
+	"strings"
+	"unicode"
+)
+
+// consulMetaValueMax is the longest value Consul accepts in service meta
+const consulMetaValueMax = 512
+
+// ConsulMeta is Tags reshaped for Consul service meta, whose keys may only contain ASCII letters, digits, dashes
+// and underscores
+func (s Snapshot) ConsulMeta() map[string]string {
+	tags := s.Tags()
+	ret := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if len(v) > consulMetaValueMax {
+			v = v[:consulMetaValueMax]
+		}
+		ret[consulMetaKey(k)] = v
+	}
+	return ret
+}
+
+func consulMetaKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			return r
+		}
+		return '_'
+	}, k)
+}
+
+// EurekaMetadata is Tags reshaped for Eureka instance metadata.  Eureka serves metadata as XML elements, so keys
+// are camel cased without the colons and dashes of the tag names: aws:ecs:clusterName becomes awsEcsClusterName.
+func (s Snapshot) EurekaMetadata() map[string]string {
+	tags := s.Tags()
+	ret := make(map[string]string, len(tags))
+	for k, v := range tags {
+		ret[eurekaMetadataKey(k)] = v
+	}
+	return ret
+}
+
+func eurekaMetadataKey(k string) string {
+	parts := strings.FieldsFunc(k, func(r rune) bool {
+		return !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+	})
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
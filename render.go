@@ -0,0 +1,115 @@
+package awsexpvar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Format is an output format for RenderTo
+type Format int
+
+// Formats understood by RenderTo
+const (
+	// FormatJSON is the same compact JSON expvar publishes
+	FormatJSON Format = iota
+	// FormatIndentedJSON is JSON for humans
+	FormatIndentedJSON
+	// FormatText is one sorted "path=value" line per leaf, convenient for log dumps
+	FormatText
+	// FormatHTML is a collapsible tree for people, with failed sections highlighted
+	FormatHTML
+	// FormatMermaid is Snapshot.Mermaid: a diagram of the instance, its network and its task, not the data itself
+	FormatMermaid
+	// FormatDOT is Snapshot.DOT, the same diagram for Graphviz
+	FormatDOT
+)
+
+// RenderMap returns what Var would publish, for consumers that do not use expvar such as custom admin endpoints
+func (e *Expvar) RenderMap(ctx context.Context) (map[string]interface{}, error) {
+	ret := e.current(ctx)
+	return ret, ctx.Err()
+}
+
+// RenderTo writes what Var would publish to w in format
+func (e *Expvar) RenderTo(ctx context.Context, w io.Writer, format Format) error {
+	m, err := e.RenderMap(ctx)
+	if err != nil {
+		return err
+	}
+	return render(w, m, format)
+}
+
+func render(w io.Writer, m map[string]interface{}, format Format) error {
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(m)
+	case FormatIndentedJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	case FormatText:
+		return renderText(w, m)
+	case FormatHTML:
+		return renderHTML(w, m)
+	case FormatMermaid:
+		_, err := io.WriteString(w, Snapshot(m).Mermaid())
+		return err
+	case FormatDOT:
+		_, err := io.WriteString(w, Snapshot(m).DOT())
+		return err
+	}
+	return fmt.Errorf("unknown format %d", format)
+}
+
+func renderText(w io.Writer, m map[string]interface{}) error {
+	// Round trip through JSON so structs flatten the same way they are published
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var generic interface{}
+	if err := decodeJSON(b, &generic); err != nil {
+		return err
+	}
+	lines := make([]string, 0, 64)
+	flatten("", generic, func(path string, val string) {
+		lines = append(lines, path+"="+val)
+	})
+	sort.Strings(lines)
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func flatten(prefix string, v interface{}, emit func(path string, val string)) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			flatten(join(k), child, emit)
+		}
+	case []interface{}:
+		for i, child := range val {
+			flatten(join(strconv.Itoa(i)), child, emit)
+		}
+	case string:
+		emit(prefix, strconv.Quote(val))
+	default:
+		b, _ := json.Marshal(val)
+		emit(prefix, string(b))
+	}
+}
@@ -0,0 +1,69 @@
+package awsexpvar
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// agentVersionPattern pulls the version out of the agent's "Amazon ECS Agent - v1.57.1 (089b7b64)"
+var agentVersionPattern = regexp.MustCompile(`v\d+\.\d+\.\d+`)
+
+type containerRuntime struct {
+	Runtime      string `json:"runtime,omitempty"`
+	Source       string `json:"source,omitempty"`
+	LaunchType   string `json:"launch-type,omitempty"`
+	AgentVersion string `json:"agent-version,omitempty"`
+}
+
+// containerRuntimeInfo is the best guess at which runtime runs this container.  Neither the agent nor task metadata
+// report the runtime's own version, so the agent version is what is exposed next to it.  Returns nil outside ECS.
+func containerRuntimeInfo(ecs interface{}, task *taskMetadata) interface{} {
+	ret := containerRuntime{
+		AgentVersion: agentVersionPattern.FindString(lookupString(ecs, "/"+lookupString(ecs, "ApiVersion")+"/metadata", "Version")),
+	}
+	if task != nil {
+		ret.LaunchType = task.LaunchType
+	}
+	if b, err := ioutil.ReadFile(cgroupFile); err == nil {
+		ret.Runtime = cgroupRuntime(string(b))
+		ret.Source = "cgroup"
+	}
+	if ret.Runtime == "" {
+		switch {
+		case ret.LaunchType == "FARGATE":
+			// Fargate platform version 1.4.0 replaced docker with containerd
+			ret.Runtime, ret.Source = "containerd", "launch-type"
+		case ret.AgentVersion != "":
+			ret.Runtime, ret.Source = "docker", "ecs-agent"
+		default:
+			ret.Source = ""
+		}
+	}
+	if ret == (containerRuntime{}) {
+		return nil
+	}
+	return ret
+}
+
+// cgroupRuntime reads the runtime out of cgroup paths such as /docker/<id>, /system.slice/docker-<id>.scope or
+// /kubepods/.../cri-containerd-<id>.scope.  ECS's own
+// /ecs/<task>/<container> paths and cgroup v2's "0::/" name nothing.
+func cgroupRuntime(cgroups string) string {
+	for _, line := range strings.Split(cgroups, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		path := parts[2]
+		switch {
+		case strings.Contains(path, "containerd"):
+			return "containerd"
+		case strings.Contains(path, "docker"):
+			return "docker"
+		case strings.Contains(path, "crio"):
+			return "cri-o"
+		}
+	}
+	return ""
+}
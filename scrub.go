@@ -0,0 +1,109 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultScrubPatterns match the secrets most often pasted into user-data and tags: AWS access key IDs, bearer
+// tokens, and PEM blocks such as private keys
+var DefaultScrubPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:AKIA|ASIA|AROA|AIDA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+	regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----[\s\S]*?-----END [A-Z0-9 ]+-----`),
+}
+
+// scrub replaces every match of ScrubPatterns in the strings of ret, at any depth
+func (e *Expvar) scrub(ctx context.Context, ret map[string]interface{}) {
+	if len(e.ScrubPatterns) == 0 {
+		return
+	}
+	for name, section := range ret {
+		ret[name] = scrubValue(section, e.ScrubPatterns, name, func(path string) {
+			noteRedaction(ctx, path, "scrub pattern")
+		})
+	}
+}
+
+// scrubValue returns v with matches of patterns replaced, changing maps and slices in place.  Values of other types,
+// such as the structs of derived sections, are only replaced by their generic JSON form when they contain a match.
+func scrubValue(v interface{}, patterns []*regexp.Regexp, path string, scrubbed func(path string)) interface{} {
+	join := func(key string) string {
+		return path + "/" + strings.TrimSuffix(key, "/")
+	}
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return scrubString(val, patterns, path, scrubbed)
+	case map[string]interface{}:
+		for k, child := range val {
+			val[k] = scrubValue(child, patterns, join(k), scrubbed)
+		}
+		return val
+	case map[string]string:
+		for k, child := range val {
+			val[k] = scrubString(child, patterns, join(k), scrubbed)
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = scrubValue(child, patterns, join(strconv.Itoa(i)), scrubbed)
+		}
+		return val
+	case json.Number, bool, error:
+		return val
+	}
+	b, err := json.Marshal(v)
+	if err != nil || !anyMatch(b, patterns) {
+		return v
+	}
+	var generic interface{}
+	if err := decodeJSON(b, &generic); err != nil {
+		return v
+	}
+	return scrubValue(generic, patterns, path, scrubbed)
+}
+
+func scrubString(s string, patterns []*regexp.Regexp, path string, scrubbed func(path string)) string {
+	matched := false
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			s = p.ReplaceAllLiteralString(s, redactedValue)
+			matched = true
+		}
+	}
+	if matched {
+		scrubbed(path)
+	}
+	return s
+}
+
+func anyMatch(b []byte, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.Match(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileScrubPatterns compiles the scrub-patterns of a Config, where "default" stands for DefaultScrubPatterns
+func compileScrubPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	ret := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "default" {
+			ret = append(ret, DefaultScrubPatterns...)
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, re)
+	}
+	return ret, nil
+}
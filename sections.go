@@ -0,0 +1,113 @@
+package awsexpvar
+
+import (
+	"errors"
+	"sort"
+)
+
+// errNotApplicable marks a source that does not exist on this platform, as opposed to one that failed
+var errNotApplicable = errors.New("not applicable on this platform")
+
+// errDisabled marks a source listed in DisabledSections
+var errDisabled = errors.New("disabled by configuration")
+
+// Section statuses reported under "_sections"
+const (
+	SectionOK            = "ok"
+	SectionEmpty         = "empty"
+	SectionUnavailable   = "unavailable"
+	SectionNotApplicable = "not-applicable"
+	SectionDisabled      = "disabled"
+)
+
+type sectionStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// sectionStatuses tells "this source does not exist here" apart from "this source exists but was empty or
+// unreachable", which both used to simply be missing from the output
+type sectionStatuses map[string]sectionStatus
+
+// record stores val under name when err is nil, and how the source fared either way
+func (s sectionStatuses) record(ret map[string]interface{}, name string, val interface{}, err error) {
+	switch {
+	case err == errDisabled:
+		s[name] = sectionStatus{Status: SectionDisabled}
+	case err == errNotApplicable:
+		s[name] = sectionStatus{Status: SectionNotApplicable}
+	case err == ErrNotFound:
+		s[name] = sectionStatus{Status: SectionEmpty}
+	case err != nil:
+		s[name] = sectionStatus{Status: SectionUnavailable, Error: err.Error()}
+	case isEmpty(val):
+		s[name] = sectionStatus{Status: SectionEmpty}
+	default:
+		s[name] = sectionStatus{Status: SectionOK}
+		ret[name] = val
+	}
+}
+
+func isEmpty(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	case map[string]string:
+		return len(v) == 0
+	}
+	return false
+}
+
+// SetSectionEnabled enables or disables the top level key name at runtime, whatever DisabledSections says.  It takes
+// effect from the next fetch or Refresh.
+func (e *Expvar) SetSectionEnabled(name string, enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.sectionOverrides == nil {
+		e.sectionOverrides = make(map[string]bool)
+	}
+	e.sectionOverrides[name] = enabled
+}
+
+// disabledSections is every section currently disabled, by DisabledSections or at runtime
+func (e *Expvar) disabledSections() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ret := make([]string, 0, len(e.DisabledSections)+len(e.sectionOverrides))
+	for _, name := range e.DisabledSections {
+		if enabled, overridden := e.sectionOverrides[name]; !overridden || !enabled {
+			ret = append(ret, name)
+		}
+	}
+	for name, enabled := range e.sectionOverrides {
+		if !enabled && !containsString(e.DisabledSections, name) {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// sectionDisabled is true for names listed in DisabledSections, unless overridden by SetSectionEnabled
+func (e *Expvar) sectionDisabled(name string) bool {
+	e.mu.Lock()
+	enabled, overridden := e.sectionOverrides[name]
+	e.mu.Unlock()
+	if overridden {
+		return !enabled
+	}
+	return containsString(e.DisabledSections, name)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
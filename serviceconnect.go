@@ -0,0 +1,74 @@
+package awsexpvar
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+)
+
+// hostsFile is where the ECS agent injects Service Connect endpoint names
+const hostsFile = "/etc/hosts"
+
+// serviceConnectPrefix is the name the ECS agent gives the Service Connect proxy container it adds to a task
+const serviceConnectPrefix = "ecs-service-connect-"
+
+// serviceConnectNet is the loopback range the ECS agent assigns Service Connect endpoints out of
+var serviceConnectNet = &net.IPNet{
+	IP:   net.IPv4(127, 255, 0, 0),
+	Mask: net.CIDRMask(16, 32),
+}
+
+type serviceConnect struct {
+	Agent     *containerStatus    `json:",omitempty"`
+	Endpoints map[string][]string `json:",omitempty"`
+}
+
+// serviceConnect returns nil if Service Connect is not enabled for the task
+func (e *Expvar) serviceConnect(t *taskMetadata) interface{} {
+	var ret serviceConnect
+	for i := range t.Containers {
+		if strings.HasPrefix(t.Containers[i].Name, serviceConnectPrefix) {
+			status := t.Containers[i].status()
+			ret.Agent = &status
+		}
+	}
+	if ret.Agent == nil {
+		return nil
+	}
+	endpoints, err := serviceConnectEndpoints(hostsFile)
+	if err != nil {
+		return err
+	}
+	ret.Endpoints = endpoints
+	return ret
+}
+
+// serviceConnectEndpoints maps each Service Connect IP in a hosts file to the names that resolve to it
+func serviceConnectEndpoints(path string) (map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	ret := make(map[string][]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil || !serviceConnectNet.Contains(ip) {
+			continue
+		}
+		ret[fields[0]] = append(ret[fields[0]], fields[1:]...)
+	}
+	return ret, scanner.Err()
+}
@@ -0,0 +1,49 @@
+package awsexpvar
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// RefreshOnSIGHUP installs a SIGHUP handler that re-reads configuration taken from the environment (such as
+// AWS_CA_BUNDLE) and then calls Refresh, the usual convention for on-host reconfiguration.  Call the returned
+// function, which is safe to call more than once, to uninstall the handler; it is also uninstalled once ctx ends.
+func (e *Expvar) RefreshOnSIGHUP(ctx context.Context) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-signals:
+				e.reloadEnv()
+				if err := e.Refresh(ctx); err != nil && e.Log != nil {
+					e.Log.Log("err", err, "unable to refresh on SIGHUP")
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// reloadEnv drops anything that was built from environment variables, so the next use re-reads them
+func (e *Expvar) reloadEnv() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.credClient = nil
+	e.credClientErr = nil
+}
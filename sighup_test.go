@@ -0,0 +1,48 @@
+package awsexpvar
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRefreshOnSIGHUP(t *testing.T) {
+	s := serveTask(t, "web")
+	e := &Expvar{Client: testClient()}
+	stop := e.RefreshOnSIGHUP(context.Background())
+	defer stop()
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skip("cannot send SIGHUP:", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(&s.requests) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("SIGHUP did not refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Wait out the Refresh the signal started, then make sure Var serves what it stored
+	time.Sleep(100 * time.Millisecond)
+	s.setFamily("api")
+	if family := publishedFamily(t, e); family != "web" {
+		t.Errorf("family %q, want web from the SIGHUP refresh", family)
+	}
+}
+
+func TestRefreshOnSIGHUPStop(t *testing.T) {
+	var e Expvar
+	stop := e.RefreshOnSIGHUP(context.Background())
+	stop()
+	stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	stop = e.RefreshOnSIGHUP(ctx)
+	cancel()
+	stop()
+}
@@ -0,0 +1,78 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Snapshot is one rendering of every metadata source, keyed the same way as the expvar output
+type Snapshot map[string]interface{}
+
+// Snapshot returns the snapshot Var would publish right now
+func (e *Expvar) Snapshot(ctx context.Context) Snapshot {
+	return Snapshot(e.current(ctx))
+}
+
+// FetchAll crawls every metadata source now with ctx, whatever Refresh or CacheTTL have stored, so callers can cancel
+// it, bound it with a deadline and carry tracing through its requests.  The snapshot is returned even with an error:
+// ctx's if it ended first, with whatever was collected marked "truncated_walk", or an error if no source answered.
+func (e *Expvar) FetchAll(ctx context.Context) (Snapshot, error) {
+	values := e.fetch(ctx)
+	return Snapshot(values), fetchErr(ctx, values)
+}
+
+// tagSource maps a tag-style key, and the short name used by Slim, to where its value lives in a snapshot
+type tagSource struct {
+	key  string
+	slim string
+	path []string
+}
+
+// wellKnownTags follows the naming of the tags AWS itself applies wherever there is one to follow
+var wellKnownTags = []tagSource{
+	{key: "aws:ec2:instanceId", slim: "instanceId", path: []string{"meta-data", "instance-id"}},
+	{key: "aws:ec2:instanceType", slim: "instanceType", path: []string{"meta-data", "instance-type"}},
+	{key: "aws:ec2:imageId", slim: "imageId", path: []string{"meta-data", "ami-id"}},
+	{key: "aws:ec2:availabilityZone", slim: "availabilityZone", path: []string{"meta-data", "placement", "availability-zone"}},
+	{key: "aws:ec2:privateIp", slim: "privateIp", path: []string{"meta-data", "local-ipv4"}},
+	{key: "aws:ec2:hostname", slim: "hostname", path: []string{"meta-data", "local-hostname"}},
+	{key: "aws:ec2:publicIp", slim: "publicIp", path: []string{"meta-data", "public-ipv4"}},
+	{key: "aws:region", slim: "region", path: []string{"instance-identity", "region"}},
+	{key: "aws:accountId", slim: "accountId", path: []string{"instance-identity", "accountId"}},
+	{key: "aws:autoscaling:groupName", slim: "autoScalingGroup", path: []string{"instance-tags", "tags", "aws:autoscaling:groupName"}},
+	{key: "aws:ec2launchtemplate:id", slim: "launchTemplateId", path: []string{"instance-tags", "tags", "aws:ec2launchtemplate:id"}},
+	{key: "aws:cloudformation:stack-name", slim: "stackName", path: []string{"instance-tags", "tags", "aws:cloudformation:stack-name"}},
+	{key: "aws:ecs:clusterName", slim: "cluster", path: []string{"task-metadata", "Cluster"}},
+	{key: "aws:ecs:taskArn", slim: "taskArn", path: []string{"task-metadata", "TaskARN"}},
+	{key: "aws:ecs:taskDefinitionFamily", slim: "taskFamily", path: []string{"task-metadata", "Family"}},
+	{key: "aws:ecs:taskDefinitionRevision", slim: "taskRevision", path: []string{"task-metadata", "Revision"}},
+	{key: "aws:ecs:launchType", slim: "launchType", path: []string{"task-metadata", "LaunchType"}},
+}
+
+// Tags flattens the snapshot into well known tag-style keys, for embedding into heartbeats, service registries or
+// Consul metadata.  Keys without a value are left out.
+func (s Snapshot) Tags() map[string]string {
+	return s.wellKnown(func(t tagSource) string { return t.key })
+}
+
+// Slim is the same values as Tags under short keys: the identity of this process, small enough to ship to other
+// systems on every heartbeat
+func (s Snapshot) Slim() map[string]string {
+	return s.wellKnown(func(t tagSource) string { return t.slim })
+}
+
+func (s Snapshot) wellKnown(keyOf func(tagSource) string) map[string]string {
+	ret := make(map[string]string, len(wellKnownTags))
+	for _, tag := range wellKnownTags {
+		if val := lookupString(map[string]interface{}(s), tag.path...); val != "" {
+			ret[keyOf(tag)] = val
+		}
+	}
+	return ret
+}
+
+// EphemeralNodePayload is Slim as compact JSON, sized for ephemeral node registration in etcd or ZooKeeper based
+// discovery systems
+func (s Snapshot) EphemeralNodePayload() ([]byte, error) {
+	return json.Marshal(s.Slim())
+}
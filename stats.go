@@ -0,0 +1,187 @@
+package awsexpvar
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindowSize is how many recent requests per source the latency stats are computed over
+const latencyWindowSize = 128
+
+// Sources latency is tracked under
+const (
+	sourceIMDS         = "imds"
+	sourceECSAgent     = "ecs-agent"
+	sourceTaskMetadata = "task-metadata"
+	sourceCredentials  = "credentials"
+	sourceOther        = "other"
+)
+
+type sourceKey struct{}
+
+// withSource labels the requests made with ctx for the latency stats
+func withSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+func sourceFrom(ctx context.Context) string {
+	if source, ok := ctx.Value(sourceKey{}).(string); ok {
+		return source
+	}
+	return sourceOther
+}
+
+// latencyWindow is a ring buffer of the most recent request latencies of one source
+type latencyWindow struct {
+	samples [latencyWindowSize]time.Duration
+	next    int
+	full    bool
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencyWindowSize
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+type latencySummary struct {
+	Count int     `json:"count"`
+	P50Ms float64 `json:"p50-ms"`
+	P95Ms float64 `json:"p95-ms"`
+	MaxMs float64 `json:"max-ms"`
+}
+
+func (w *latencyWindow) summary() latencySummary {
+	n := w.next
+	if w.full {
+		n = latencyWindowSize
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	if n == 0 {
+		return latencySummary{}
+	}
+	return latencySummary{
+		Count: n,
+		P50Ms: millis(sorted[(n-1)*50/100]),
+		P95Ms: millis(sorted[(n-1)*95/100]),
+		MaxMs: millis(sorted[n-1]),
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// stats are exposed under "_stats" so IMDS slowness trends are visible before they become timeouts
+type stats struct {
+	mu         sync.Mutex
+	latency    map[string]*latencyWindow
+	tokens     tokenStats
+	redactions redactionStats
+}
+
+// redactionStats count the values each fetch left out, so a jump, such as a secret showing up in new user-data, can
+// be alerted on
+type redactionStats struct {
+	fetches  int64
+	total    int64
+	last     int64
+	byReason map[string]int64
+}
+
+type redactionSummary struct {
+	Last         int64            `json:"last"`
+	LastByReason map[string]int64 `json:"last-by-reason"`
+	Total        int64            `json:"total"`
+}
+
+// recordRedactions replaces the counts of the previous fetch with those of redactions
+func (s *stats) recordRedactions(redactions []redaction) {
+	byReason := make(map[string]int64)
+	for _, r := range redactions {
+		byReason[r.reason]++
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redactions.fetches++
+	s.redactions.total += int64(len(redactions))
+	s.redactions.last = int64(len(redactions))
+	s.redactions.byReason = byReason
+}
+
+// tokenStats track IMDSv2 session tokens, whose acquisition fails in ways of its own such as hop limits
+type tokenStats struct {
+	acquired int64
+	failures int64
+	expires  time.Time
+}
+
+type tokenSummary struct {
+	Acquired            int64    `json:"acquired"`
+	Failures            int64    `json:"failures"`
+	TTLRemainingSeconds *float64 `json:"ttl-remaining-seconds,omitempty"`
+}
+
+// recordToken counts a token request, and remembers when the newest token expires if one was handed out
+func (s *stats) recordToken(expires time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.tokens.failures++
+		return
+	}
+	s.tokens.acquired++
+	s.tokens.expires = expires
+}
+
+func (s *stats) recordLatency(source string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.latency == nil {
+		s.latency = make(map[string]*latencyWindow)
+	}
+	w, exists := s.latency[source]
+	if !exists {
+		w = &latencyWindow{}
+		s.latency[source] = w
+	}
+	w.add(d)
+}
+
+func (s *stats) export() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latency := make(map[string]latencySummary, len(s.latency))
+	for source, w := range s.latency {
+		latency[source] = w.summary()
+	}
+	ret := map[string]interface{}{
+		"latency": latency,
+	}
+	if s.tokens.acquired > 0 || s.tokens.failures > 0 {
+		summary := tokenSummary{
+			Acquired: s.tokens.acquired,
+			Failures: s.tokens.failures,
+		}
+		if !s.tokens.expires.IsZero() {
+			remaining := math.Max(0, time.Until(s.tokens.expires).Seconds())
+			summary.TTLRemainingSeconds = &remaining
+		}
+		ret["imds-token"] = summary
+	}
+	if s.redactions.fetches > 0 {
+		ret["redactions"] = redactionSummary{
+			Last:         s.redactions.last,
+			LastByReason: s.redactions.byReason,
+			Total:        s.redactions.total,
+		}
+	}
+	return ret
+}
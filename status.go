@@ -0,0 +1,39 @@
+package awsexpvar
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// statusError is an answer outside 2xx other than 404.  It marshals to its message, so the reason shows up wherever the crawl stores
+// the error in place of a value.
+type statusError struct {
+	source string
+	code   int
+}
+
+func (s *statusError) Error() string {
+	switch {
+	case s.source == sourceIMDS && s.code == http.StatusUnauthorized:
+		return "IMDSv2 token required: the instance requires session tokens but none could be obtained." +
+			"  From a container, raise the instance's HttpPutResponseHopLimit to 2."
+	case s.source == sourceIMDS && s.code == http.StatusForbidden:
+		return "forbidden: the instance metadata service is disabled for this instance or this path"
+	case s.source == sourceCredentials && s.code == http.StatusUnauthorized:
+		return "unauthorized: the credentials endpoint refused the authorization token"
+	}
+	return s.source + " answered " + strconv.Itoa(s.code) + " " + http.StatusText(s.code)
+}
+
+func (s *statusError) MarshalText() ([]byte, error) {
+	return []byte(s.Error()), nil
+}
+
+// classifyStatus turns answers outside 2xx into errors instead of bodies that look like metadata, such as the error
+// page of a 500 published as task metadata
+func classifyStatus(source string, code int) error {
+	if code < 200 || code >= 300 {
+		return &statusError{source: source, code: code}
+	}
+	return nil
+}
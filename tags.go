@@ -0,0 +1,48 @@
+package awsexpvar
+
+import "context"
+
+// Tag sources reported under "instance-tags"
+const (
+	TagSourceIMDS   = "imds"
+	TagSourceEC2API = "ec2-api"
+)
+
+// InstanceTagger looks up instance tags when IMDS does not serve them.  The awssdk module implements it with
+// ec2:DescribeTags.
+type InstanceTagger interface {
+	InstanceTags(ctx context.Context, region string, instanceID string) (map[string]string, error)
+}
+
+// instanceTags prefers the IMDS tags endpoint, which only exists when instance metadata tags are enabled, and falls
+// back to TagFallback otherwise
+func (e *Expvar) instanceTags(ctx context.Context, metaData interface{}, identity interface{}) interface{} {
+	keys := children(metaData, "tags", "instance")
+	if len(keys) > 0 {
+		tags := make(map[string]string, len(keys))
+		for _, k := range keys {
+			tags[k] = lookupString(metaData, "tags", "instance", k)
+		}
+		return instanceTagsSection(TagSourceIMDS, tags)
+	}
+	if e.TagFallback == nil {
+		return nil
+	}
+	instanceID := lookupString(metaData, "instance-id")
+	region := lookupString(identity, "region")
+	if instanceID == "" || region == "" {
+		return nil
+	}
+	tags, err := e.TagFallback.InstanceTags(ctx, region, instanceID)
+	if err != nil {
+		return err.Error()
+	}
+	return instanceTagsSection(TagSourceEC2API, tags)
+}
+
+func instanceTagsSection(source string, tags map[string]string) map[string]interface{} {
+	return map[string]interface{}{
+		"source": source,
+		"tags":   tags,
+	}
+}
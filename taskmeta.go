@@ -0,0 +1,347 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taskMetadata is the subset of the task metadata response (${ECS_CONTAINER_METADATA_URI_V4}/task) we interpret
+type taskMetadata struct {
+	Cluster       string
+	TaskARN       string
+	Family        string
+	Revision      string
+	DesiredStatus string
+	KnownStatus   string
+	LaunchType    string
+	// AvailabilityZone is only in v4 responses
+	AvailabilityZone string
+	Limits           *resourceLimits
+	PullStartedAt    string
+	PullStoppedAt    string
+	Containers       []taskContainer
+}
+
+type taskContainer struct {
+	DockerID      string `json:"DockerId"`
+	Name          string
+	ContainerARN  string
+	DockerName    string
+	Image         string
+	ImageID       string
+	DesiredStatus string
+	KnownStatus   string
+	ExitCode      *int
+	Reason        string
+	CreatedAt     string
+	StartedAt     string
+	Limits        *resourceLimits
+	Volumes       []containerVolume
+	Labels        map[string]string
+}
+
+type containerVolume struct {
+	DockerName      string `json:",omitempty"`
+	Source          string
+	Destination     string
+	EFSFileSystemID string `json:"EfsFileSystemId,omitempty"`
+}
+
+// efsFileSystemID finds the file system ID the ECS agent embeds in the source of EFS volume mounts
+var efsFileSystemID = regexp.MustCompile(`fs-[0-9a-f]{8,17}`)
+
+// resourceLimits is the Limits object of a task or a container.  Memory is in MiB for both, but task CPU is in vCPUs
+// while container CPU is in CPU units (1024 per vCPU).  MemoryBytes and MemoryHuman are only set with HumanSizes.
+type resourceLimits struct {
+	CPU         float64 `json:"CPU,omitempty"`
+	Memory      int64   `json:"Memory,omitempty"`
+	MemoryBytes int64   `json:"MemoryBytes,omitempty"`
+	MemoryHuman string  `json:"MemoryHuman,omitempty"`
+}
+
+// withSizes copies l with the memory limit also in bytes and in IEC units
+func (l *resourceLimits) withSizes() *resourceLimits {
+	if l == nil || l.Memory == 0 {
+		return l
+	}
+	ret := *l
+	ret.MemoryBytes = l.Memory << 20
+	ret.MemoryHuman = humanBytes(ret.MemoryBytes)
+	return &ret
+}
+
+// humanBytes writes n in the largest IEC unit that keeps it at least 1, with at most one decimal, such as "512MiB"
+// or "1.5GiB"
+func humanBytes(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	v := float64(n)
+	i := 0
+	for ; v >= 1024 && i < len(units)-1; i++ {
+		v /= 1024
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(v, 'f', 1, 64), ".0") + units[i]
+}
+
+// taskMetadataURL picks v4 of the task metadata endpoint, or v3 on platforms old enough to only offer v3.  Both
+// answer /task with the same layout.
+func (e *Expvar) taskMetadataURL() (string, string) {
+	if base := os.Getenv("ECS_CONTAINER_METADATA_URI_V4"); base != "" {
+		return base, "v4"
+	}
+	if base := os.Getenv("ECS_CONTAINER_METADATA_URI"); base != "" {
+		return base, "v3"
+	}
+	return "", ""
+}
+
+// taskMetadata returns both the parsed task metadata and the raw object we expose
+func (e *Expvar) taskMetadata(ctx context.Context) (*taskMetadata, map[string]interface{}, error) {
+	base, _ := e.taskMetadataURL()
+	if base == "" {
+		return nil, nil, errNotApplicable
+	}
+	b, err := e.getBody(withSource(ctx, sourceTaskMetadata), base+"/task")
+	if err != nil {
+		return nil, nil, err
+	}
+	raw := make(map[string]interface{})
+	if err := decodeJSON(b, &raw); err != nil {
+		return nil, nil, err
+	}
+	var t taskMetadata
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, raw, nil
+	}
+	return &t, raw, nil
+}
+
+// limits separates task level limits from container level limits.  They are frequently confused when investigating
+// OOM kills: a container can be killed by its own limit well before the task limit is reached.  humanSizes adds each
+// memory limit in bytes and in human readable form.
+func (t *taskMetadata) limits(humanSizes bool) map[string]interface{} {
+	sized := func(l *resourceLimits) *resourceLimits {
+		if humanSizes {
+			return l.withSizes()
+		}
+		return l
+	}
+	containers := make(map[string]*resourceLimits, len(t.Containers))
+	for _, c := range t.Containers {
+		containers[c.Name] = sized(c.Limits)
+	}
+	return map[string]interface{}{
+		"task":       sized(t.Limits),
+		"containers": containers,
+	}
+}
+
+type containerImage struct {
+	Image   string
+	ImageID string
+}
+
+// images lets deploys be verified against the digest that is actually running, not just the tag that was pushed
+func (t *taskMetadata) images() map[string]containerImage {
+	ret := make(map[string]containerImage, len(t.Containers))
+	for _, c := range t.Containers {
+		ret[c.Name] = containerImage{
+			Image:   c.Image,
+			ImageID: c.ImageID,
+		}
+	}
+	return ret
+}
+
+type containerStatus struct {
+	DesiredStatus string
+	KnownStatus   string
+	ExitCode      *int   `json:",omitempty"`
+	Reason        string `json:",omitempty"`
+}
+
+// statuses exposes every container of the task, so a crashed sidecar is visible from the main container
+func (t *taskMetadata) statuses() map[string]containerStatus {
+	ret := make(map[string]containerStatus, len(t.Containers))
+	for _, c := range t.Containers {
+		ret[c.Name] = c.status()
+	}
+	return ret
+}
+
+func (c *taskContainer) status() containerStatus {
+	return containerStatus{
+		DesiredStatus: c.DesiredStatus,
+		KnownStatus:   c.KnownStatus,
+		ExitCode:      c.ExitCode,
+		Reason:        c.Reason,
+	}
+}
+
+// volumes exposes each container's mounts, with the EFS file system ID pulled out of the source where there is one
+func (t *taskMetadata) volumes() map[string][]containerVolume {
+	ret := make(map[string][]containerVolume, len(t.Containers))
+	for _, c := range t.Containers {
+		if len(c.Volumes) == 0 {
+			continue
+		}
+		vols := make([]containerVolume, 0, len(c.Volumes))
+		for _, v := range c.Volumes {
+			v.EFSFileSystemID = efsFileSystemID.FindString(v.Source)
+			vols = append(vols, v)
+		}
+		ret[c.Name] = vols
+	}
+	return ret
+}
+
+// imageCreatedLabels are the labels image builds record their build time in, most standard first
+var imageCreatedLabels = []string{"org.opencontainers.image.created", "org.label-schema.build-date", "build-date"}
+
+type imageAge struct {
+	Label             string    `json:"label"`
+	ImageCreated      time.Time `json:"image-created"`
+	StartedAt         time.Time `json:"started-at"`
+	AgeAtStartSeconds float64   `json:"age-at-start-seconds"`
+}
+
+// imageAges shows, for each started container whose image records its build time in a label, how old the image was
+// when the container started, which gives away deploys of stale images
+func (t *taskMetadata) imageAges() interface{} {
+	ret := make(map[string]imageAge)
+	for _, c := range t.Containers {
+		started, err := time.Parse(time.RFC3339Nano, c.StartedAt)
+		if err != nil {
+			continue
+		}
+		for _, label := range imageCreatedLabels {
+			created, err := time.Parse(time.RFC3339Nano, c.Labels[label])
+			if err != nil {
+				continue
+			}
+			ret[c.Name] = imageAge{
+				Label:             label,
+				ImageCreated:      created,
+				StartedAt:         started,
+				AgeAtStartSeconds: started.Sub(created).Seconds(),
+			}
+			break
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// labels exposes the Docker labels of each container that are in allowlist, which is how many teams record deploy
+// metadata such as the commit or pipeline run
+func (t *taskMetadata) labels(allowlist []string) interface{} {
+	ret := make(map[string]map[string]string)
+	for _, c := range t.Containers {
+		for label, value := range c.Labels {
+			if !labelAllowed(label, allowlist) {
+				continue
+			}
+			if ret[c.Name] == nil {
+				ret[c.Name] = make(map[string]string)
+			}
+			ret[c.Name][label] = value
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// labelAllowed matches label against allowlist, where a trailing "*" matches by prefix
+func labelAllowed(label string, allowlist []string) bool {
+	for _, allowed := range allowlist {
+		if prefix := strings.TrimSuffix(allowed, "*"); prefix != allowed {
+			if strings.HasPrefix(label, prefix) {
+				return true
+			}
+		} else if label == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// parsedTaskMetadata is taskMetadata for callers that only want the parsed form
+func (e *Expvar) parsedTaskMetadata(ctx context.Context) (*taskMetadata, error) {
+	task, _, err := e.taskMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, errors.New("unable to parse task metadata")
+	}
+	return task, nil
+}
+
+// taskStats is the Docker stats of every container of the task, keyed by DockerID, or errNotApplicable unless
+// TaskStats is set
+func (e *Expvar) taskStats(ctx context.Context) (interface{}, error) {
+	base, _ := e.taskMetadataURL()
+	if !e.TaskStats || base == "" {
+		return nil, errNotApplicable
+	}
+	b, err := e.getBody(withSource(ctx, sourceTaskMetadata), base+"/task/stats")
+	if err != nil {
+		return nil, err
+	}
+	var ret map[string]interface{}
+	if err := decodeJSON(b, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Where the "ecs-metadata" section came from, under its "Source" key
+const (
+	ecsSourceAgent        = "agent"
+	ecsSourceTaskMetadata = "task-metadata"
+)
+
+// ecsSection is the "ecs-metadata" section for tasks that have a task metadata endpoint
+func (t *taskMetadata) ecsSection() map[string]interface{} {
+	return map[string]interface{}{
+		"Source":           ecsSourceTaskMetadata,
+		"Cluster":          t.Cluster,
+		"TaskARN":          t.TaskARN,
+		"Family":           t.Family,
+		"Revision":         t.Revision,
+		"LaunchType":       t.LaunchType,
+		"AvailabilityZone": t.AvailabilityZone,
+	}
+}
+
+// launchTypesWithoutIMDS are the launch types whose tasks do not run on EC2: Fargate, and ECS Anywhere's on-premises
+// EXTERNAL instances.  Each maps to its AWS_EXECUTION_ENV.
+var launchTypesWithoutIMDS = map[string]string{
+	"FARGATE":  "AWS_ECS_FARGATE",
+	"EXTERNAL": "AWS_ECS_EXTERNAL",
+}
+
+// withoutIMDS is true for tasks that have no IMDS to ask, by launch type or, when task metadata did not answer, by
+// AWS_EXECUTION_ENV
+func withoutIMDS(t *taskMetadata) bool {
+	if t != nil && t.LaunchType != "" {
+		_, exists := launchTypesWithoutIMDS[t.LaunchType]
+		return exists
+	}
+	env := os.Getenv("AWS_EXECUTION_ENV")
+	for _, launchEnv := range launchTypesWithoutIMDS {
+		if env == launchEnv {
+			return true
+		}
+	}
+	return false
+}
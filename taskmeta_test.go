@@ -0,0 +1,98 @@
+package awsexpvar
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// loopbackOnly fails every request that is not to a loopback address, such as the link-local metadata endpoints,
+// so tests never wait on endpoints that do not exist outside AWS
+type loopbackOnly struct{}
+
+func (loopbackOnly) RoundTrip(r *http.Request) (*http.Response, error) {
+	if ip := net.ParseIP(r.URL.Hostname()); ip == nil || !ip.IsLoopback() {
+		return nil, errors.New("test transport only reaches loopback: " + r.URL.Host)
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func testClient() *http.Client {
+	return &http.Client{Transport: loopbackOnly{}}
+}
+
+// serveJSON answers every path in bodies with its JSON body, and 404 otherwise
+func serveJSON(t *testing.T, bodies map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, exists := bodies[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// published decodes what the Var of e publishes
+func published(t *testing.T, e *Expvar) map[string]interface{} {
+	t.Helper()
+	ret := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(e.Var().String()), &ret); err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+const testTask = `{
+	"Cluster": "default",
+	"TaskARN": "arn:aws:ecs:us-west-2:123456789012:task/default/0123456789abcdef",
+	"Family": "web",
+	"Revision": "7",
+	"Limits": {"CPU": 0.25, "Memory": 512},
+	"Containers": [
+		{"DockerId": "abc", "Name": "app", "Limits": {"CPU": 128, "Memory": 256}},
+		{"DockerId": "def", "Name": "sidecar"}
+	]
+}`
+
+func TestLimits(t *testing.T) {
+	srv := serveJSON(t, map[string]string{"/task": testTask})
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", srv.URL)
+	ret := published(t, &Expvar{Client: testClient()})
+	limits, _ := ret["limits"].(map[string]interface{})
+	task, _ := limits["task"].(map[string]interface{})
+	if task["CPU"] != 0.25 || task["Memory"] != float64(512) {
+		t.Errorf("task limits %v, want CPU 0.25 and Memory 512", task)
+	}
+	containers, _ := limits["containers"].(map[string]interface{})
+	app, _ := containers["app"].(map[string]interface{})
+	if app["CPU"] != float64(128) || app["Memory"] != float64(256) {
+		t.Errorf("app limits %v, want CPU 128 and Memory 256", app)
+	}
+	if sidecar, exists := containers["sidecar"]; !exists || sidecar != nil {
+		t.Errorf("sidecar limits %v, want null", sidecar)
+	}
+}
+
+func TestTaskMetadataServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(testTask))
+	}))
+	defer srv.Close()
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", srv.URL)
+	ret := published(t, &Expvar{Client: testClient()})
+	if task, isMap := ret["task-metadata"].(map[string]interface{}); isMap {
+		t.Errorf("task-metadata %v, want the 500 answer left out", task)
+	}
+	if limits, _ := ret["limits"].(map[string]interface{}); limits["task"] != nil {
+		t.Errorf("limits %v from a 500 answer", limits)
+	}
+}
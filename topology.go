@@ -0,0 +1,113 @@
+package awsexpvar
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// topology is a small graph of what this process runs on: instance to ENIs to subnets and VPCs, and task to
+// containers
+type topology struct {
+	labels []string
+	ids    map[string]string
+	edges  [][2]string
+}
+
+// node returns the ID of the node keyed key, adding it labelled label the first time
+func (t *topology) node(key string, label string) string {
+	if id, exists := t.ids[key]; exists {
+		return id
+	}
+	id := "n" + strconv.Itoa(len(t.labels))
+	t.ids[key] = id
+	t.labels = append(t.labels, label)
+	return id
+}
+
+// nodeLabel joins the non-empty lines of a node label
+func nodeLabel(lines ...string) string {
+	kept := lines[:0]
+	for _, line := range lines {
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func (t *topology) edge(from string, to string) {
+	t.edges = append(t.edges, [2]string{from, to})
+}
+
+func (s Snapshot) topology() *topology {
+	t := &topology{ids: make(map[string]string)}
+	tree := map[string]interface{}(s)
+	typed := s.Typed()
+	var instance string
+	if id := lookupString(tree, "meta-data", "instance-id"); id != "" {
+		instance = t.node("instance", nodeLabel("instance "+id, lookupString(tree, "meta-data", "instance-type")))
+		macs := children(tree, "meta-data", "network", "interfaces", "macs")
+		sort.Strings(macs)
+		for _, mac := range macs {
+			field := func(name string) string {
+				return lookupString(tree, "meta-data", "network", "interfaces", "macs", mac, name)
+			}
+			eniLabel := field("interface-id")
+			if eniLabel == "" {
+				eniLabel = mac
+			}
+			eni := t.node("eni/"+mac, nodeLabel("ENI "+eniLabel, strings.Join(strings.Fields(field("local-ipv4s")), ", ")))
+			t.edge(instance, eni)
+			if subnetID := field("subnet-id"); subnetID != "" {
+				subnet := t.node("subnet/"+subnetID, nodeLabel("subnet "+subnetID, field("subnet-ipv4-cidr-block")))
+				t.edge(eni, subnet)
+				if vpcID := field("vpc-id"); vpcID != "" {
+					t.edge(subnet, t.node("vpc/"+vpcID, "VPC "+vpcID))
+				}
+			}
+		}
+	}
+	if typed.ECSTask != nil {
+		task := t.node("task", nodeLabel("task "+typed.ECSTask.Family+":"+typed.ECSTask.Revision,
+			typed.ECSTask.LaunchType))
+		if instance != "" {
+			t.edge(instance, task)
+		}
+		for _, c := range typed.Containers {
+			t.edge(task, t.node("container/"+c.Name, nodeLabel("container "+c.Name, c.Image, c.KnownStatus)))
+		}
+	}
+	return t
+}
+
+// Mermaid renders the topology of s as a mermaid flowchart, for wikis and incident docs that embed mermaid
+func (s Snapshot) Mermaid() string {
+	t := s.topology()
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for i, label := range t.labels {
+		label = strings.ReplaceAll(label, `"`, "#quot;")
+		b.WriteString("  n" + strconv.Itoa(i) + `["` + strings.ReplaceAll(label, "\n", "<br>") + "\"]\n")
+	}
+	for _, e := range t.edges {
+		b.WriteString("  " + e[0] + " --> " + e[1] + "\n")
+	}
+	return b.String()
+}
+
+// DOT renders the topology of s as a Graphviz digraph
+func (s Snapshot) DOT() string {
+	t := s.topology()
+	var b strings.Builder
+	b.WriteString("digraph aws {\n  rankdir=LR;\n  node [shape=box];\n")
+	for i, label := range t.labels {
+		label = strings.ReplaceAll(strings.ReplaceAll(label, `\`, `\\`), `"`, `\"`)
+		b.WriteString("  n" + strconv.Itoa(i) + ` [label="` + strings.ReplaceAll(label, "\n", `\n`) + "\"];\n")
+	}
+	for _, e := range t.edges {
+		b.WriteString("  " + e[0] + " -> " + e[1] + ";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
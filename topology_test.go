@@ -0,0 +1,65 @@
+package awsexpvar
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func topologySnapshot(t *testing.T) Snapshot {
+	t.Helper()
+	var task map[string]interface{}
+	if err := json.Unmarshal([]byte(testTask), &task); err != nil {
+		t.Fatal(err)
+	}
+	return Snapshot{
+		"meta-data": map[string]interface{}{
+			"instance-id":   "i-0123456789abcdef0",
+			"instance-type": "m5.large",
+			"network/": map[string]interface{}{"interfaces/": map[string]interface{}{"macs/": map[string]interface{}{
+				"0e:00:00:00:00:01/": map[string]string{
+					"interface-id":           "eni-1",
+					"local-ipv4s":            "10.0.0.5\n10.0.0.6",
+					"subnet-id":              "subnet-1",
+					"subnet-ipv4-cidr-block": "10.0.0.0/24",
+					"vpc-id":                 "vpc-1",
+				},
+			}}},
+		},
+		"task-metadata": task,
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	want := `graph LR
+  n0["instance i-0123456789abcdef0<br>m5.large"]
+  n1["ENI eni-1<br>10.0.0.5, 10.0.0.6"]
+  n2["subnet subnet-1<br>10.0.0.0/24"]
+  n3["VPC vpc-1"]
+  n4["task web:7"]
+  n5["container app"]
+  n6["container sidecar"]
+  n0 --> n1
+  n1 --> n2
+  n2 --> n3
+  n0 --> n4
+  n4 --> n5
+  n4 --> n6
+`
+	if got := topologySnapshot(t).Mermaid(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDOT(t *testing.T) {
+	got := topologySnapshot(t).DOT()
+	for _, line := range []string{"digraph aws {", `n0 [label="instance i-0123456789abcdef0\nm5.large"];`,
+		"n2 -> n3;", "n4 -> n6;"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("%q missing from\n%s", line, got)
+		}
+	}
+	if empty := (Snapshot{}).DOT(); strings.Contains(empty, "->") {
+		t.Errorf("empty snapshot has edges:\n%s", empty)
+	}
+}
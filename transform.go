@@ -0,0 +1,30 @@
+package awsexpvar
+
+import "strings"
+
+// transform rewrites one leaf of every fetch
+type transform struct {
+	path []string
+	fn   func(string) string
+}
+
+// Transform registers fn to rewrite the leaf at path, such as "meta-data/placement/availability-zone", before every
+// publication.  Segments match the way Get matches them.  fn is given the leaf without its trailing newline, and is
+// not called when the leaf is missing.  Transforms run in the order they were registered.
+func (e *Expvar) Transform(path string, fn func(string) string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.transforms = append(e.transforms, transform{
+		path: strings.Split(strings.Trim(path, "/"), "/"),
+		fn:   fn,
+	})
+}
+
+func (e *Expvar) applyTransforms(ret map[string]interface{}) {
+	e.mu.Lock()
+	transforms := e.transforms
+	e.mu.Unlock()
+	for _, t := range transforms {
+		transformLeaf(ret, t.path, t.fn)
+	}
+}
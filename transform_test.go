@@ -0,0 +1,25 @@
+package awsexpvar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{fileEndpoint(t, testInstance)}}
+	e.Transform("meta-data/placement/availability-zone", strings.ToUpper)
+	e.Transform("/meta-data/placement/availability-zone/", func(s string) string { return s + "-zone" })
+	e.Transform("meta-data/missing", func(string) string {
+		t.Error("transform called for a missing leaf")
+		return ""
+	})
+	metaData, _ := published(t, e)["meta-data"].(map[string]interface{})
+	placement, _ := metaData["placement/"].(map[string]interface{})
+	if az := placement["availability-zone"]; az != "US-WEST-2A-zone" {
+		t.Errorf("availability-zone %v, want both transforms in order", az)
+	}
+	if region := placement["region"]; region != "us-west-2" {
+		t.Errorf("untransformed region %v", region)
+	}
+}
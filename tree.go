@@ -0,0 +1,105 @@
+package awsexpvar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// lookup walks a crawled metadata tree.  Directories are keyed with a trailing slash by the crawl, so each segment
+// matches either "name" or "name/".  JSON leaves the crawl decoded into flat string maps can be walked into as well.
+func lookup(tree interface{}, path ...string) interface{} {
+	cur := tree
+	for _, segment := range path {
+		if leaves, ok := cur.(map[string]string); ok {
+			if v, exists := leaves[segment]; exists {
+				cur = v
+				continue
+			}
+			return nil
+		}
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if v, exists := m[segment]; exists {
+			cur = v
+			continue
+		}
+		if v, exists := m[segment+"/"]; exists {
+			cur = v
+			continue
+		}
+		return nil
+	}
+	return cur
+}
+
+// lookupString is lookup for leaves, which the crawl stores as strings
+func lookupString(tree interface{}, path ...string) string {
+	if s, ok := lookup(tree, path...).(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return ""
+}
+
+// children lists the keys of a crawled directory without their trailing slash
+func children(tree interface{}, path ...string) []string {
+	m, ok := lookup(tree, path...).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, strings.TrimSuffix(k, "/"))
+	}
+	return ret
+}
+
+// transformLeaf replaces the string leaf at path with fn of it
+func transformLeaf(tree interface{}, path []string, fn func(string) string) {
+	if len(path) == 0 {
+		return
+	}
+	last := path[len(path)-1]
+	switch parent := lookup(tree, path[:len(path)-1]...).(type) {
+	case map[string]string:
+		if v, exists := parent[last]; exists {
+			parent[last] = fn(strings.TrimSpace(v))
+		}
+	case map[string]interface{}:
+		if v, ok := parent[last].(string); ok {
+			parent[last] = fn(strings.TrimSpace(v))
+		}
+	}
+}
+
+// removePath deletes the value at path, leaf or directory
+func removePath(tree interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	last := path[len(path)-1]
+	switch parent := lookup(tree, path[:len(path)-1]...).(type) {
+	case map[string]string:
+		delete(parent, last)
+	case map[string]interface{}:
+		delete(parent, last)
+		delete(parent, last+"/")
+	}
+}
+
+// decodeJSON is json.Unmarshal with numbers kept as json.Number.  Metadata carries IDs too large for a float64, which
+// would otherwise be published rounded and in scientific notation.
+func decodeJSON(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
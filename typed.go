@@ -0,0 +1,96 @@
+package awsexpvar
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// TypedSnapshot is the part of a Snapshot programs most often read, as structs instead of maps.  Sections that did
+// not answer are nil or empty.  Everything else is still in Raw.
+type TypedSnapshot struct {
+	InstanceIdentity *InstanceIdentity
+	ECSTask          *ECSTask
+	// Containers are the containers of ECSTask
+	Containers []Container
+	UserData   string
+	// MetaData is the IMDS meta-data tree, with directories keyed with a trailing slash
+	MetaData map[string]interface{}
+	Raw      Snapshot
+}
+
+// InstanceIdentity is the instance identity document of IMDS
+type InstanceIdentity struct {
+	AccountID        string `json:"accountId"`
+	Architecture     string `json:"architecture"`
+	AvailabilityZone string `json:"availabilityZone"`
+	ImageID          string `json:"imageId"`
+	InstanceID       string `json:"instanceId"`
+	InstanceType     string `json:"instanceType"`
+	PendingTime      string `json:"pendingTime"`
+	PrivateIP        string `json:"privateIp"`
+	Region           string `json:"region"`
+}
+
+// ECSTask is the task of the ECS task metadata endpoint
+type ECSTask struct {
+	Cluster          string
+	TaskARN          string
+	Family           string
+	Revision         string
+	DesiredStatus    string
+	KnownStatus      string
+	LaunchType       string
+	AvailabilityZone string
+}
+
+// Container is one container of an ECSTask
+type Container struct {
+	Name          string
+	DockerID      string `json:"DockerId"`
+	ContainerARN  string
+	Image         string
+	ImageID       string
+	DesiredStatus string
+	KnownStatus   string
+	ExitCode      *int
+}
+
+// TypedSnapshot is Snapshot, typed
+func (e *Expvar) TypedSnapshot(ctx context.Context) TypedSnapshot {
+	return e.Snapshot(ctx).Typed()
+}
+
+// Typed reads the well known sections of s into a TypedSnapshot
+func (s Snapshot) Typed() TypedSnapshot {
+	ret := TypedSnapshot{Raw: s}
+	var identity InstanceIdentity
+	if convert(s["instance-identity"], &identity) {
+		ret.InstanceIdentity = &identity
+	}
+	var task struct {
+		ECSTask
+		Containers []Container
+	}
+	if convert(s["task-metadata"], &task) {
+		ret.ECSTask = &task.ECSTask
+		ret.Containers = task.Containers
+	}
+	ret.UserData, _ = s["user-data"].(string)
+	ret.MetaData, _ = s["meta-data"].(map[string]interface{})
+	return ret
+}
+
+// convert fills out from a section of a snapshot, whether it is still as crawled or was decoded from a stored
+// snapshot, and is false when the section is missing or not an object
+func convert(section interface{}, out interface{}) bool {
+	switch section.(type) {
+	case map[string]interface{}, map[string]string:
+	default:
+		return false
+	}
+	b, err := json.Marshal(section)
+	if err != nil {
+		return false
+	}
+	return decodeJSON(b, out) == nil
+}
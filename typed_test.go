@@ -0,0 +1,36 @@
+package awsexpvar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	var task map[string]interface{}
+	if err := json.Unmarshal([]byte(testTask), &task); err != nil {
+		t.Fatal(err)
+	}
+	snap := Snapshot{
+		"instance-identity": map[string]string{"region": "us-west-2", "instanceId": "i-0123456789abcdef0"},
+		"task-metadata":     task,
+		"user-data":         "#!/bin/bash",
+		"meta-data":         map[string]interface{}{"instance-type": "m5.large"},
+	}
+	typed := snap.Typed()
+	if typed.InstanceIdentity == nil || typed.InstanceIdentity.Region != "us-west-2" ||
+		typed.InstanceIdentity.InstanceID != "i-0123456789abcdef0" {
+		t.Errorf("instance identity %+v", typed.InstanceIdentity)
+	}
+	if typed.ECSTask == nil || typed.ECSTask.Family != "web" || typed.ECSTask.Revision != "7" {
+		t.Errorf("task %+v", typed.ECSTask)
+	}
+	if len(typed.Containers) != 2 || typed.Containers[0].DockerID != "abc" {
+		t.Errorf("containers %+v", typed.Containers)
+	}
+	if typed.UserData != "#!/bin/bash" || typed.MetaData["instance-type"] != "m5.large" {
+		t.Errorf("user-data %q, meta-data %v", typed.UserData, typed.MetaData)
+	}
+	if empty := (Snapshot{}).Typed(); empty.InstanceIdentity != nil || empty.ECSTask != nil {
+		t.Errorf("empty snapshot typed as %+v", empty)
+	}
+}
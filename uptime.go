@@ -0,0 +1,67 @@
+package awsexpvar
+
+import "time"
+
+// processStart approximates when this process started
+var processStart = time.Now()
+
+type uptime struct {
+	ProcessStartedAt    time.Time  `json:"process-started-at"`
+	ProcessSeconds      float64    `json:"process-seconds"`
+	TaskPulledAt        *time.Time `json:"task-pulled-at,omitempty"`
+	TaskStartedAt       *time.Time `json:"task-started-at,omitempty"`
+	TaskSeconds         *float64   `json:"task-seconds,omitempty"`
+	InstancePendingTime *time.Time `json:"instance-pending-time,omitempty"`
+	InstanceSeconds     *float64   `json:"instance-seconds,omitempty"`
+}
+
+// computeUptime lines up process, task and instance start times, so hosts that have lived far longer than the
+// current deployment stand out
+func computeUptime(now time.Time, identity interface{}, task *taskMetadata) uptime {
+	ret := uptime{
+		ProcessStartedAt: processStart,
+		ProcessSeconds:   now.Sub(processStart).Seconds(),
+	}
+	if pending, ok := parseTime(lookupString(identity, "pendingTime")); ok {
+		ret.InstancePendingTime = &pending
+		ret.InstanceSeconds = secondsSince(now, pending)
+	}
+	if task != nil {
+		if pulled, ok := parseTime(task.PullStoppedAt); ok {
+			ret.TaskPulledAt = &pulled
+		}
+		if started, ok := task.startedAt(); ok {
+			ret.TaskStartedAt = &started
+			ret.TaskSeconds = secondsSince(now, started)
+		}
+	}
+	return ret
+}
+
+// startedAt is when the first container of the task started
+func (t *taskMetadata) startedAt() (time.Time, bool) {
+	var first time.Time
+	for _, c := range t.Containers {
+		started, ok := parseTime(c.StartedAt)
+		if ok && (first.IsZero() || started.Before(first)) {
+			first = started
+		}
+	}
+	return first, !first.IsZero()
+}
+
+func parseTime(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func secondsSince(now time.Time, t time.Time) *float64 {
+	s := now.Sub(t).Seconds()
+	return &s
+}
@@ -0,0 +1,70 @@
+package awsexpvar
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// sourceSections are the sections that make requests.  With all of them disabled every fetch is empty.
+var sourceSections = []string{"task-metadata", "task-stats", "meta-data", "ecs-metadata", "instance-identity",
+	"user-data", "container-metadata"}
+
+// Validate reports settings that contradict each other or cannot work, which would otherwise only show up as
+// missing or empty output.  Start calls it; other callers may call it once e is configured.
+func (e *Expvar) Validate() error {
+	var problems []string
+	for _, d := range []struct {
+		name string
+		val  time.Duration
+	}{
+		{name: "Timeout", val: e.Timeout},
+		{name: "WalkTimeout", val: e.WalkTimeout},
+		{name: "CacheTTL", val: e.CacheTTL},
+		{name: "RefreshInterval", val: e.RefreshInterval},
+	} {
+		if d.val < 0 {
+			problems = append(problems, d.name+" is negative; use zero for the default")
+		}
+	}
+	if e.MaxRequestsPerRefresh < 0 {
+		problems = append(problems, "MaxRequestsPerRefresh is negative; use zero for no cap")
+	}
+	if e.WalkConcurrency < 0 {
+		problems = append(problems, "WalkConcurrency is negative; use zero for DefaultWalkConcurrency")
+	}
+	if e.MaxDepth < 0 {
+		problems = append(problems, "MaxDepth is negative; use zero for DefaultMaxDepth")
+	}
+	if e.WalkTimeout > 0 && e.WalkTimeout < e.timeout() {
+		problems = append(problems, "WalkTimeout "+e.WalkTimeout.String()+" is shorter than the Timeout of a single "+
+			"request, "+e.timeout().String()+"; raise WalkTimeout or lower Timeout")
+	}
+	if e.CacheTTL > 0 && e.RefreshInterval > 0 {
+		problems = append(problems, "CacheTTL is unused once Start refreshes every RefreshInterval; set only one")
+	}
+	allDisabled := true
+	for _, name := range sourceSections {
+		if !e.sectionDisabled(name) {
+			allDisabled = false
+		}
+	}
+	if allDisabled {
+		problems = append(problems, "DisabledSections disables every source ("+strings.Join(sourceSections, ", ")+
+			"), so nothing would be fetched")
+	}
+	for _, name := range e.SecretEnvVars {
+		if containsString(e.EnvAllowlist, name) && !e.EnvNamesOnly {
+			problems = append(problems, name+" is in both SecretEnvVars and EnvAllowlist, so its secret value "+
+				"would be published under env; remove it from EnvAllowlist")
+		}
+	}
+	problems = append(problems, e.crawlOverlaps()...)
+	if e.EnvNamesOnly && len(e.EnvAllowlist) == 0 {
+		problems = append(problems, "EnvNamesOnly has no effect without EnvAllowlist")
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package awsexpvar
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		e       *Expvar
+		problem string
+	}{
+		{name: "zero value", e: &Expvar{}},
+		{name: "negative timeout", e: &Expvar{Timeout: -time.Second}, problem: "Timeout is negative"},
+		{name: "negative concurrency", e: &Expvar{WalkConcurrency: -1}, problem: "WalkConcurrency is negative"},
+		{
+			name:    "walk shorter than a request",
+			e:       &Expvar{Timeout: time.Second, WalkTimeout: 100 * time.Millisecond},
+			problem: "WalkTimeout 100ms is shorter",
+		},
+		{
+			name:    "cache and refresh",
+			e:       &Expvar{CacheTTL: time.Minute, RefreshInterval: time.Minute},
+			problem: "CacheTTL is unused",
+		},
+		{
+			name:    "every source disabled",
+			e:       &Expvar{DisabledSections: sourceSections},
+			problem: "disables every source",
+		},
+		{
+			name:    "secret allowlisted",
+			e:       &Expvar{SecretEnvVars: []string{"TOKEN"}, EnvAllowlist: []string{"TOKEN"}},
+			problem: "TOKEN is in both SecretEnvVars and EnvAllowlist",
+		},
+		{
+			name: "secret allowlisted by name only",
+			e:    &Expvar{SecretEnvVars: []string{"TOKEN"}, EnvAllowlist: []string{"TOKEN"}, EnvNamesOnly: true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.e.Validate()
+			if tc.problem == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.problem) {
+				t.Fatalf("got %v, want %q", err, tc.problem)
+			}
+		})
+	}
+}
+
+func TestStartValidates(t *testing.T) {
+	e := &Expvar{RefreshInterval: time.Minute, CacheTTL: time.Minute}
+	if err := e.Start(context.Background()); err == nil {
+		e.Stop()
+		t.Fatal("Start accepted an invalid configuration")
+	}
+}
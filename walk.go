@@ -0,0 +1,114 @@
+package awsexpvar
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// errRequestBudget is returned for requests past MaxRequestsPerRefresh
+var errRequestBudget = errors.New("request budget of the walk exhausted")
+
+// errMaxDepth stands in for directories deeper than MaxDepth
+var errMaxDepth = errors.New("directory deeper than the maximum crawl depth")
+
+// walk is the state shared by every request of one fetch
+type walk struct {
+	maxRequests int64
+	requests    int64
+	truncated   int32
+	// skipIMDS answers every IMDS request with errNotApplicable, for platforms known to have no IMDS
+	skipIMDS bool
+	// inFlight holds one token per request in flight, up to WalkConcurrency
+	inFlight chan struct{}
+
+	mu         sync.Mutex
+	redactions []redaction
+}
+
+// redaction is one value left out of a fetch, and why
+type redaction struct {
+	path   string
+	reason string
+}
+
+type walkKey struct{}
+
+func withWalk(ctx context.Context, w *walk) context.Context {
+	return context.WithValue(ctx, walkKey{}, w)
+}
+
+func walkFrom(ctx context.Context) *walk {
+	w, _ := ctx.Value(walkKey{}).(*walk)
+	return w
+}
+
+// takeRequest spends one request of the walk that ctx belongs to, and is false once the walk is out of requests
+func takeRequest(ctx context.Context) bool {
+	w := walkFrom(ctx)
+	if w == nil || w.maxRequests <= 0 {
+		return true
+	}
+	if atomic.AddInt64(&w.requests, 1) <= w.maxRequests {
+		return true
+	}
+	atomic.StoreInt32(&w.truncated, 1)
+	return false
+}
+
+// truncate marks the walk ctx belongs to as truncated
+func truncate(ctx context.Context) {
+	if w := walkFrom(ctx); w != nil {
+		atomic.StoreInt32(&w.truncated, 1)
+	}
+}
+
+// acquire waits for a request slot of the walk ctx belongs to.  The returned release must be called once the request
+// is done.
+func acquire(ctx context.Context) (release func(), err error) {
+	w := walkFrom(ctx)
+	if w == nil || w.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case w.inFlight <- struct{}{}:
+		return func() { <-w.inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (w *walk) wasTruncated() bool {
+	return atomic.LoadInt32(&w.truncated) == 1
+}
+
+// noteRedaction records, for LogRedactions, that the value at path was left out of the walk ctx belongs to
+func noteRedaction(ctx context.Context, path string, reason string) {
+	w := walkFrom(ctx)
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.redactions = append(w.redactions, redaction{path: path, reason: reason})
+}
+
+// recordRedactions counts what the walk left out under "_stats"
+func (e *Expvar) recordRedactions(w *walk) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	e.stats.recordRedactions(w.redactions)
+}
+
+// logRedactions tells the Logger, and only the Logger, what the walk left out
+func (e *Expvar) logRedactions(w *walk) {
+	if !e.LogRedactions || e.Log == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, r := range w.redactions {
+		e.Log.Log("path", r.path, "reason", r.reason, "redacted")
+	}
+}
@@ -0,0 +1,114 @@
+package awsexpvar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowIMDS serves a meta-data directory of leaves, each answered after a delay, and records how many leaf requests it
+// had in flight at once
+type slowIMDS struct {
+	leaves int
+	delay  time.Duration
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (m *slowIMDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Replace(r.URL.Path, "//", "/", -1)
+	if r.Method != http.MethodGet || !strings.HasPrefix(path, metadataPath) {
+		http.NotFound(w, r)
+		return
+	}
+	if path == metadataPath {
+		names := make([]string, 0, m.leaves)
+		for i := 0; i < m.leaves; i++ {
+			names = append(names, "leaf-"+strconv.Itoa(i))
+		}
+		_, _ = w.Write([]byte(strings.Join(names, "\n")))
+		return
+	}
+	if !strings.HasPrefix(path, metadataPath+"leaf-") {
+		http.NotFound(w, r)
+		return
+	}
+	m.mu.Lock()
+	m.inFlight++
+	if m.inFlight > m.maxInFlight {
+		m.maxInFlight = m.inFlight
+	}
+	m.mu.Unlock()
+	time.Sleep(m.delay)
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+	_, _ = w.Write([]byte(strings.TrimPrefix(path, metadataPath)))
+}
+
+func TestWalkConcurrency(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	imds := &slowIMDS{leaves: 12, delay: 20 * time.Millisecond}
+	srv := httptest.NewServer(imds)
+	defer srv.Close()
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{srv.URL}, WalkConcurrency: 3}
+	metaData, _ := published(t, e)["meta-data"].(map[string]interface{})
+	for i := 0; i < imds.leaves; i++ {
+		name := "leaf-" + strconv.Itoa(i)
+		if metaData[name] != name {
+			t.Errorf("%s: %v", name, metaData[name])
+		}
+	}
+	imds.mu.Lock()
+	defer imds.mu.Unlock()
+	if imds.maxInFlight > 3 {
+		t.Errorf("%d requests in flight, want at most WalkConcurrency 3", imds.maxInFlight)
+	}
+	if imds.maxInFlight < 2 {
+		t.Errorf("%d requests in flight, want the leaves fetched concurrently", imds.maxInFlight)
+	}
+}
+
+func TestWalkMaxDepth(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+	var requests int64
+	// every meta-data directory lists one more directory, forever
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, metadataPath) {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt64(&requests, 1)
+		_, _ = w.Write([]byte("next/"))
+	}))
+	defer srv.Close()
+	e := &Expvar{Client: testClient(), MetadataEndpoints: []string{srv.URL}, MaxDepth: 3}
+	ret := published(t, e)
+	if ret["truncated_walk"] != true {
+		t.Errorf("truncated_walk %v", ret["truncated_walk"])
+	}
+	// three directories are crawled, and the fourth is the errMaxDepth standing in for the rest
+	dirs := 0
+	dir, _ := ret["meta-data"].(map[string]interface{})
+	for dir != nil {
+		next, exists := dir["next/"]
+		if !exists {
+			break
+		}
+		dirs++
+		dir, _ = next.(map[string]interface{})
+	}
+	if dirs != 4 {
+		t.Errorf("%d directories, want MaxDepth 3 and the error past it", dirs)
+	}
+	if n := atomic.LoadInt64(&requests); n > 10 {
+		t.Errorf("%d requests to an endless tree", n)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/cep21/awsexpvar/yamlconfig
+
+go 1.18
+
+require (
+	github.com/cep21/awsexpvar v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/cep21/awsexpvar => ../
@@ -0,0 +1,58 @@
+// Package yamlconfig loads awsexpvar configuration and expectations from YAML files.  It is its own module so the YAML parser is only
+// a dependency of programs that want it.
+package yamlconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/cep21/awsexpvar"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig is awsexpvar.LoadConfig for YAML files.  The keys are the same as for JSON:
+//
+//	disabled-sections: [user-data]
+//	env-allowlist: [SERVICE_NAME, DEPLOY_ID]
+func LoadConfig(path string) (*awsexpvar.Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(b)
+}
+
+// ParseConfig is LoadConfig for YAML that is already in memory
+func ParseConfig(b []byte) (*awsexpvar.Config, error) {
+	asJSON, err := toJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	return awsexpvar.ParseConfig(asJSON)
+}
+
+// LoadExpectations is awsexpvar.LoadExpectations for YAML files:
+//
+//	cluster: prod
+//	family: api
+//	instance-family: m5.
+func LoadExpectations(path string) (*awsexpvar.Expectations, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	asJSON, err := toJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	return awsexpvar.ParseExpectations(asJSON)
+}
+
+// toJSON re-encodes a YAML document as JSON, so decoding, and its strictness, stays in the awsexpvar package
+func toJSON(b []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}